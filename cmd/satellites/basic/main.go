@@ -0,0 +1,28 @@
+package main
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite/basic"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	var c satellite.Config
+	err = envconfig.Process("gogarin_satellite", &c)
+	if err != nil {
+		panic(err)
+	}
+
+	logger := satellite.NewLogger(c, "version", version, "commit", commit, "build_ts", buildTime)
+	conn := satellite.NewConnection(c, logger)
+
+	sat := basic.New(conn, satellite.Logger(logger))
+
+	satellite.Run(sat, c, logger)
+}
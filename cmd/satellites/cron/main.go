@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite/cron"
+	transportredis "github.com/antonkuzmenko/gogarin/pkg/transport/redis"
+	"github.com/go-kit/kit/log/level"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	var c satellite.Config
+	err = envconfig.Process("gogarin_satellite", &c)
+	if err != nil {
+		panic(err)
+	}
+
+	var cc cron.Config
+	err = envconfig.Process("gogarin_satellite_cron", &cc)
+	if err != nil {
+		panic(err)
+	}
+
+	logger := satellite.NewLogger(c, "version", version, "commit", commit, "build_ts", buildTime)
+	conn := satellite.NewConnection(c, logger)
+
+	// The triggers are kept in the transport's database, next to the leases of the ticks.
+	pool := transportredis.NewPool(c.Transport.Redis)
+	sat := cron.New(conn, pool, cc, satellite.Logger(logger))
+
+	satellite.Run(sat, c, logger)
+
+	err = pool.Close()
+	if err != nil {
+		level.Error(logger).Log("err", err)
+	}
+}
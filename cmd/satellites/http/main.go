@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite/webhook"
+	"github.com/go-kit/kit/log/level"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	var c satellite.Config
+	err = envconfig.Process("gogarin_satellite", &c)
+	if err != nil {
+		panic(err)
+	}
+
+	var wc webhook.Config
+	err = envconfig.Process("gogarin_satellite_http", &wc)
+	if err != nil {
+		panic(err)
+	}
+
+	logger := satellite.NewLogger(c, "version", version, "commit", commit, "build_ts", buildTime)
+	conn := satellite.NewConnection(c, logger)
+
+	hooks := webhook.NewHooks(wc)
+	sat := webhook.New(conn, hooks, satellite.Logger(logger))
+
+	srv := &http.Server{
+		Addr:         wc.Address,
+		Handler:      hooks,
+		ReadTimeout:  time.Duration(wc.ReadTimeoutInMs) * time.Millisecond,
+		WriteTimeout: time.Duration(wc.WriteTimeoutInMs) * time.Millisecond,
+	}
+	go func() {
+		err := srv.ListenAndServe()
+		if err != http.ErrServerClosed {
+			level.Error(logger).Log("err", err, "address", wc.Address)
+			os.Exit(1)
+		}
+	}()
+
+	// Stop accepting the requests before the triggers are stopped.
+	satellite.Run(sat, c, logger, srv.Shutdown)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite/postgres"
+	"github.com/go-kit/kit/log/level"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	var c satellite.Config
+	err = envconfig.Process("gogarin_satellite", &c)
+	if err != nil {
+		panic(err)
+	}
+
+	var pc postgres.Config
+	err = envconfig.Process("gogarin_satellite_postgres", &pc)
+	if err != nil {
+		panic(err)
+	}
+
+	logger := satellite.NewLogger(c, "version", version, "commit", commit, "build_ts", buildTime)
+	conn := satellite.NewConnection(c, logger)
+
+	pools := postgres.NewPools(pc)
+	sat := postgres.New(conn, pools, satellite.Logger(logger))
+
+	satellite.Run(sat, c, logger)
+
+	// Close the pools after the in-flight queries are done.
+	err = pools.Close()
+	if err != nil {
+		level.Error(logger).Log("err", err)
+	}
+}
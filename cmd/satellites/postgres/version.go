@@ -0,0 +1,8 @@
+package main
+
+// set by release script, see Makefile
+var (
+	version   = "unset"
+	commit    = "unset"
+	buildTime = "unset"
+)
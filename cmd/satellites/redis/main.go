@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite/redis"
+	transportredis "github.com/antonkuzmenko/gogarin/pkg/transport/redis"
+	"github.com/go-kit/kit/log/level"
+	"github.com/joho/godotenv"
+	"github.com/kelseyhightower/envconfig"
+)
+
+func main() {
+	err := godotenv.Load(".env")
+	if err != nil {
+		panic(err)
+	}
+
+	var c satellite.Config
+	err = envconfig.Process("gogarin_satellite", &c)
+	if err != nil {
+		panic(err)
+	}
+
+	// The database the abilities run the commands against, it may differ from the transport's.
+	var rc transportredis.Config
+	err = envconfig.Process("gogarin_satellite_redis", &rc)
+	if err != nil {
+		panic(err)
+	}
+
+	logger := satellite.NewLogger(c, "version", version, "commit", commit, "build_ts", buildTime)
+	conn := satellite.NewConnection(c, logger)
+
+	pool := transportredis.NewPool(rc)
+	sat := redis.New(conn, pool, satellite.Logger(logger))
+
+	satellite.Run(sat, c, logger)
+
+	err = pool.Close()
+	if err != nil {
+		level.Error(logger).Log("err", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,6 +27,11 @@ type Config struct {
 		Redis               redis.Config
 		PollTimeoutInMs     int `default:"2000"`
 		ShutdownTimeoutInMs int `default:"30000"`
+		// ValidateTimeoutInMs limits the time a satellite has to validate the config of a mission step.
+		ValidateTimeoutInMs int `default:"5000"`
+		// Namespaces lists the tenants served by the space center,
+		// e.g. GOGARIN_SPACE_CENTER_TRANSPORT_NAMESPACES=staging,production.
+		Namespaces []string
 	}
 	Logger   string `default:"json"`
 	Database struct {
@@ -48,7 +54,12 @@ func main() {
 
 	logger := newLogger(config)
 	conn := newConn(config, logger)
-	_ = openDBConnection(config, logger)
+	store := satelliteStore{db: openDBConnection(config, logger)}
+	err = store.migrate(context.Background())
+	if err != nil {
+		level.Error(logger).Log("err", err, "context", "migrate")
+		os.Exit(1)
+	}
 
 	enc := func(ctx context.Context, res interface{}) (response interface{}, err error) {
 		var buf bytes.Buffer
@@ -59,21 +70,105 @@ func main() {
 		return buf.Bytes(), nil
 	}
 
+	var servers []*transport.Server
+	for _, ns := range namespaces(config) {
+		server := newServer(config, conn, store, enc, log.With(logger, "namespace", ns), ns)
+		servers = append(servers, server)
+		go func() {
+			er := server.Serve()
+			if er != transport.ErrServerClosed {
+				level.Error(log.With(logger, "component", "transport.Server")).Log("err", er, "context", "Serve")
+			}
+		}()
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigs
+	level.Info(logger).Log("sig", sig)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(config.Transport.ShutdownTimeoutInMs)*time.Millisecond,
+	)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *transport.Server) {
+			defer wg.Done()
+
+			er := server.Shutdown(ctx)
+			if er != nil {
+				level.Error(logger).Log("err", er)
+				for topic, st := range server.Stats().Topics {
+					level.Warn(logger).Log("topic", topic, "in_flight", st.InFlight, "requeued", st.Requeued)
+				}
+			}
+		}(server)
+	}
+	wg.Wait()
+}
+
+// namespaces returns the tenant namespaces served by the space center.
+// Without configured namespaces, the topics are not namespaced.
+func namespaces(c Config) []string {
+	if len(c.Transport.Namespaces) == 0 {
+		return []string{""}
+	}
+	return c.Transport.Namespaces
+}
+
+// newServer creates a transport.Server serving the topics of a single tenant.
+// Servers of different namespaces never see each other's requests.
+func newServer(
+	c Config,
+	conn transport.Connection,
+	store satelliteStore,
+	enc transport.EncodeResponseFunc,
+	logger log.Logger,
+	namespace string,
+) *transport.Server {
 	registerEndpoint := func(ctx context.Context, req interface{}) (res interface{}, err error) {
-		i := req.(satellite.Info)
-		level.Info(logger).Log("name", i.Name, "version", i.Version)
-		return i, nil
+		r := req.(satellite.Registration)
+		level.Info(logger).Log(
+			"name", r.Info.Name,
+			"version", r.Info.Version,
+			"instance", r.Instance,
+			"abilities", len(r.Abilities),
+		)
+
+		err = store.save(ctx, namespace, r)
+		if err != nil {
+			return nil, err
+		}
+
+		broken, err := brokenMissions(ctx, store, namespace, r)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range broken {
+			level.Warn(logger).Log(
+				"name", r.Info.Name,
+				"version", r.Info.Version,
+				"mission", m.ID,
+				"err", m.Errors,
+				"context", "upgrade",
+			)
+		}
+		return registerResponse{Info: r.Info, BrokenMissions: broken}, nil
 	}
 
 	registerDec := func(ctx context.Context, req interface{}) (res interface{}, err error) {
 		r := req.([]byte)
-		var i satellite.Info
-		err = json.Unmarshal(r, &i)
+		var reg satellite.Registration
+		err = json.Unmarshal(r, &reg)
 		if err != nil {
 			return nil, err
 		}
 
-		return i, nil
+		return reg, nil
 	}
 
 	register := redis.NewServer(
@@ -82,32 +177,22 @@ func main() {
 	)
 	server := transport.NewServer(
 		conn,
-		time.Duration(config.Transport.PollTimeoutInMs)*time.Millisecond,
+		time.Duration(c.Transport.PollTimeoutInMs)*time.Millisecond,
 		log.With(logger, "component", "transport.Server"),
+		transport.ServerNamespace(namespace),
 	)
 	server.Handle("satellite.register", register)
-	go func() {
-		er := server.Serve()
-		if er != transport.ErrServerClosed {
-			level.Error(log.With(logger, "component", "transport.Server")).Log("err", er, "context", "Serve")
-		}
-	}()
-
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	sig := <-sigs
-	level.Info(logger).Log("sig", sig)
-
-	ctx, cancel := context.WithTimeout(
-		context.Background(),
-		time.Duration(config.Transport.ShutdownTimeoutInMs)*time.Millisecond,
+	validateConfig := makeConfigValidator(
+		conn,
+		namespace,
+		time.Duration(c.Transport.ValidateTimeoutInMs)*time.Millisecond,
+		log.With(logger, "component", "transport.Client"),
 	)
-	defer cancel()
-
-	err = server.Shutdown(ctx)
-	if err != nil {
-		level.Error(logger).Log("err", err)
-	}
+	server.Handle("mission.save", redis.NewServer(
+		makeSaveMissionEndpoint(store, namespace, validateConfig), decodeSaveMissionRequest, enc,
+		redis.ServerLogger(log.With(logger, "component", "redis.Server")),
+	))
+	return server
 }
 
 const (
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/antonkuzmenko/gogarin/pkg/transport/redis"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// mission is a chain of steps, each step receives the messages of the previous one.
+type mission struct {
+	ID    string
+	Name  string
+	Steps []step
+}
+
+// step is an ability of a satellite configured for a mission.
+type step struct {
+	Satellite string
+	Version   string
+	Kind      satellite.Kind
+	Ability   string
+	Config    json.RawMessage
+}
+
+// saveMissionResponse lists the reasons the mission has not been saved, if any.
+type saveMissionResponse struct {
+	ID     string
+	Errors schema.ValidationErrors `json:",omitempty"`
+}
+
+// configValidator asks the satellite to validate the config of its ability,
+// see satellite.Satellite.ValidateConfig. Input describes the messages the step receives, if known.
+// It returns the messages the step outputs with the config, if the satellite describes them,
+// see satellite.Satellite.DescribeOutput.
+type configValidator func(
+	ctx context.Context,
+	a satellite.AbilityManifest,
+	config json.RawMessage,
+	input schema.Fields,
+) (schema.ValidationErrors, schema.Fields, error)
+
+// validateMission checks that every step refers to a registered ability,
+// that its config matches the ability's config schema and that the satellite accepts it
+// for the messages of the previous step.
+func validateMission(
+	ctx context.Context,
+	store registrations,
+	namespace string,
+	validateConfig configValidator,
+	m mission,
+) (schema.ValidationErrors, error) {
+	var errs schema.ValidationErrors
+	if m.ID == "" {
+		errs = append(errs, schema.ValidationError{Path: "ID", Message: "is required"})
+	}
+	if len(m.Steps) == 0 {
+		errs = append(errs, schema.ValidationError{Path: "Steps", Message: "is required"})
+	}
+
+	// input describes the messages of the previous step, it is nil if they are unknown.
+	var input schema.Fields
+	for i, st := range m.Steps {
+		path := fmt.Sprintf("Steps[%d]", i)
+
+		ability, err := findAbility(ctx, store, namespace, st)
+		if err == errUnknownStep {
+			errs = append(errs, schema.ValidationError{Path: path, Message: err.Error()})
+			input = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		err = schema.ValidateJSON(ability.Config, st.Config)
+		if err != nil {
+			errs = append(errs, err.(schema.ValidationErrors).Prefix(path+".Config")...)
+			input = stepOutput(ability, input, nil)
+			continue
+		}
+
+		configErrs, output, err := validateConfig(ctx, ability, st.Config, input)
+		if err != nil {
+			return nil, err
+		}
+		errs = append(errs, configErrs.Prefix(path+".Config")...)
+		input = stepOutput(ability, input, output)
+	}
+
+	return errs, nil
+}
+
+// stepOutput describes the messages a step passes to the next one.
+// The output described by the satellite for the step's config takes precedence over the ability's Output.
+// A filter without Output passes the messages it receives.
+func stepOutput(a satellite.AbilityManifest, input, output schema.Fields) schema.Fields {
+	if output != nil {
+		return output
+	}
+	if a.Kind == satellite.KindFilter && a.Output == nil {
+		return input
+	}
+	return a.Output
+}
+
+var errUnknownStep = fmt.Errorf("unknown satellite, version or ability")
+
+// findAbility returns the manifest of the step's ability.
+// It returns errUnknownStep if the ability has not been registered.
+func findAbility(
+	ctx context.Context,
+	store registrations,
+	namespace string,
+	st step,
+) (satellite.AbilityManifest, error) {
+	r, err := store.find(ctx, namespace, st.Satellite, st.Version)
+	if err == sql.ErrNoRows {
+		return satellite.AbilityManifest{}, errUnknownStep
+	}
+	if err != nil {
+		return satellite.AbilityManifest{}, err
+	}
+
+	for _, a := range r.Abilities {
+		if a.Kind == st.Kind && a.Name == st.Ability {
+			return a, nil
+		}
+	}
+	return satellite.AbilityManifest{}, errUnknownStep
+}
+
+func makeSaveMissionEndpoint(store satelliteStore, namespace string, validateConfig configValidator) endpoint.Endpoint {
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		m := req.(mission)
+
+		errs, err := validateMission(ctx, store, namespace, validateConfig, m)
+		if err != nil {
+			return nil, err
+		}
+		if len(errs) > 0 {
+			return saveMissionResponse{ID: m.ID, Errors: errs}, nil
+		}
+
+		err = store.saveMission(ctx, namespace, m)
+		if err != nil {
+			return nil, err
+		}
+		return saveMissionResponse{ID: m.ID}, nil
+	}
+}
+
+// makeConfigValidator returns a configValidator sending satellite.ValidateConfigRequest
+// to the ability's ValidateTopic. Configs of satellites that do not respond within the timeout,
+// e.g. because no instance is running, are invalid: the satellite could reject them.
+func makeConfigValidator(
+	conn transport.Connection,
+	namespace string,
+	timeout time.Duration,
+	logger log.Logger,
+) configValidator {
+	return func(
+		ctx context.Context,
+		a satellite.AbilityManifest,
+		config json.RawMessage,
+		input schema.Fields,
+	) (schema.ValidationErrors, schema.Fields, error) {
+		if a.ValidateTopic == "" {
+			// Registered by a satellite that cannot validate configs.
+			return nil, nil, nil
+		}
+
+		validate := transport.NewClient(
+			conn,
+			a.ValidateTopic,
+			timeout,
+			encodeJSONRequest,
+			decodeValidateConfigResponse,
+			transport.ClientNamespace(namespace),
+		).Endpoint()
+
+		req := satellite.ValidateConfigRequest{Config: config}
+		if input != nil {
+			req.Input = schema.ToJSONSchema(input)
+		}
+		res, err := validate(ctx, req)
+		if err != nil {
+			level.Warn(logger).Log("err", err, "topic", a.ValidateTopic, "context", "validate config")
+			return schema.ValidationErrors{{Message: "could not be validated by the satellite: " + err.Error()}}, nil, nil
+		}
+
+		r := res.(satellite.ValidateConfigResponse)
+		if r.Error != "" && len(r.Errors) == 0 {
+			return schema.ValidationErrors{{Message: r.Error}}, nil, nil
+		}
+		if len(r.Errors) > 0 || r.Output == nil {
+			return r.Errors, nil, nil
+		}
+
+		output, err := schema.FromJSONSchema(r.Output)
+		if err != nil {
+			level.Warn(logger).Log("err", err, "topic", a.ValidateTopic, "context", "describe output")
+			return nil, nil, nil
+		}
+		return nil, output, nil
+	}
+}
+
+func encodeJSONRequest(_ context.Context, req interface{}) (interface{}, error) {
+	return json.Marshal(req)
+}
+
+func decodeValidateConfigResponse(_ context.Context, res interface{}) (interface{}, error) {
+	err := redis.DecodeError(res.([]byte))
+	if err != nil {
+		return nil, err
+	}
+
+	var r satellite.ValidateConfigResponse
+	err = json.Unmarshal(res.([]byte), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decodeSaveMissionRequest(_ context.Context, req interface{}) (interface{}, error) {
+	var m mission
+	err := json.Unmarshal(req.([]byte), &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
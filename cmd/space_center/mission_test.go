@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/go-kit/kit/log"
+)
+
+func TestValidateMission(t *testing.T) {
+	store := memRegistrations{}
+	store.add("jira", "1", satellite.AbilityManifest{Kind: satellite.KindTrigger, Name: "created", Output: issue})
+	store.add("labels", "1", satellite.AbilityManifest{Kind: satellite.KindFilter, Name: "has"})
+	store.add("mail", "1", satellite.AbilityManifest{
+		Kind:   satellite.KindAction,
+		Name:   "send",
+		Config: schema.Fields{"to": {Name: "To", Type: schema.String, Required: true}},
+	})
+
+	// inputs records the input each ability has been validated with.
+	inputs := make(map[string]schema.Fields)
+	validate := func(
+		ctx context.Context,
+		a satellite.AbilityManifest,
+		config json.RawMessage,
+		input schema.Fields,
+	) (schema.ValidationErrors, schema.Fields, error) {
+		inputs[a.Name] = input
+		if string(config) == `{"to":"nobody"}` {
+			return schema.ValidationErrors{{Path: "to", Message: "is unknown"}}, nil, nil
+		}
+		return nil, nil, nil
+	}
+
+	m := mission{ID: "m", Steps: []step{
+		{Satellite: "jira", Version: "1", Kind: satellite.KindTrigger, Ability: "created"},
+		{Satellite: "labels", Version: "1", Kind: satellite.KindFilter, Ability: "has"},
+		{Satellite: "mail", Version: "1", Kind: satellite.KindAction, Ability: "send", Config: []byte(`{"to":"nobody"}`)},
+		{Satellite: "mail", Version: "1", Kind: satellite.KindAction, Ability: "send", Config: []byte(`{}`)},
+	}}
+	errs, err := validateMission(context.Background(), store, "", validate, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := schema.ValidationErrors{
+		{Path: "Steps[2].Config.to", Message: "is unknown"},
+		{Path: "Steps[3].Config.to", Message: "is required"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+	if !reflect.DeepEqual(inputs["has"], issue) || !reflect.DeepEqual(inputs["send"], issue) {
+		t.Errorf("the filter does not pass the trigger's output on, got %v", inputs)
+	}
+
+	// The messages after an unknown step are unknown.
+	inputs = make(map[string]schema.Fields)
+	m = mission{Steps: []step{
+		{Satellite: "jira", Version: "2", Kind: satellite.KindTrigger, Ability: "created"},
+		{Satellite: "mail", Version: "1", Kind: satellite.KindAction, Ability: "send", Config: []byte(`{"to":"a"}`)},
+	}}
+	errs, err = validateMission(context.Background(), store, "", validate, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = schema.ValidationErrors{
+		{Path: "ID", Message: "is required"},
+		{Path: "Steps[0]", Message: errUnknownStep.Error()},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+	if input, ok := inputs["send"]; !ok || input != nil {
+		t.Errorf("got the input %v after an unknown step, want nil", input)
+	}
+
+	errs, _ = validateMission(context.Background(), store, "", validate, mission{ID: "m"})
+	want = schema.ValidationErrors{{Path: "Steps", Message: "is required"}}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("got %v, want %v", errs, want)
+	}
+}
+
+func TestStepOutput(t *testing.T) {
+	input := schema.Fields{"title": {Name: "Title", Type: schema.String}}
+	output := schema.Fields{"count": {Name: "Count", Type: schema.Integer}}
+	described := schema.Fields{"total": {Name: "Total", Type: schema.Integer}}
+
+	tests := []struct {
+		name      string
+		ability   satellite.AbilityManifest
+		input     schema.Fields
+		described schema.Fields
+		want      schema.Fields
+	}{
+		{"filter passthrough", satellite.AbilityManifest{Kind: satellite.KindFilter}, input, nil, input},
+		{"filter passthrough of unknown", satellite.AbilityManifest{Kind: satellite.KindFilter}, nil, nil, nil},
+		{"filter with output", satellite.AbilityManifest{Kind: satellite.KindFilter, Output: output}, input, nil, output},
+		{"action", satellite.AbilityManifest{Kind: satellite.KindAction, Output: output}, input, nil, output},
+		{"action without output", satellite.AbilityManifest{Kind: satellite.KindAction}, input, nil, nil},
+		{"described", satellite.AbilityManifest{Kind: satellite.KindAction, Output: output}, input, described, described},
+		{"described filter", satellite.AbilityManifest{Kind: satellite.KindFilter}, input, described, described},
+	}
+	for _, tt := range tests {
+		got := stepOutput(tt.ability, tt.input, tt.described)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// unreachable is a transport.Connection to a satellite that never responds.
+type unreachable struct {
+	err error
+}
+
+func (u unreachable) Send(topic, replyTopic string, data interface{}) error {
+	return u.err
+}
+
+func (u unreachable) Receive(topic string, timeout time.Duration) (string, interface{}, error) {
+	return "", nil, transport.ErrTimeout
+}
+
+func (u unreachable) Ping() error {
+	return u.err
+}
+
+func TestConfigValidatorUnavailable(t *testing.T) {
+	a := satellite.AbilityManifest{Kind: satellite.KindAction, Name: "send", ValidateTopic: "mail.send.validate"}
+
+	for _, conn := range []transport.Connection{unreachable{}, unreachable{err: errors.New("broken pipe")}} {
+		validate := makeConfigValidator(conn, "", time.Millisecond, log.NewNopLogger())
+		errs, output, err := validate(context.Background(), a, json.RawMessage(`{}`), nil)
+		if err != nil || output != nil {
+			t.Fatalf("got %v, %v", output, err)
+		}
+		if len(errs) != 1 || errs[0].Path != "" || !strings.HasPrefix(errs[0].Message, "could not be validated") {
+			t.Errorf("got %v", errs)
+		}
+	}
+
+	a.ValidateTopic = ""
+	validate := makeConfigValidator(unreachable{}, "", time.Millisecond, log.NewNopLogger())
+	errs, _, err := validate(context.Background(), a, json.RawMessage(`{}`), nil)
+	if errs != nil || err != nil {
+		t.Errorf("got %v, %v for an ability without a validate topic", errs, err)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+)
+
+// satelliteStore keeps the manifests of the registered satellites.
+type satelliteStore struct {
+	db *sql.DB
+}
+
+const createSatellitesTable = `
+CREATE TABLE IF NOT EXISTS satellites (
+	namespace     TEXT NOT NULL,
+	name          TEXT NOT NULL,
+	version       TEXT NOT NULL,
+	description   TEXT NOT NULL,
+	manifest      JSONB NOT NULL,
+	registered_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (namespace, name, version)
+)`
+
+const createMissionsTable = `
+CREATE TABLE IF NOT EXISTS missions (
+	namespace  TEXT NOT NULL,
+	id         TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	steps      JSONB NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+	PRIMARY KEY (namespace, id)
+)`
+
+// migrate creates the tables used by the store.
+func (s satelliteStore) migrate(ctx context.Context) error {
+	for _, query := range []string{createSatellitesTable, createMissionsTable} {
+		_, err := s.db.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save creates or updates the manifest of the satellite version.
+func (s satelliteStore) save(ctx context.Context, namespace string, r satellite.Registration) error {
+	const query = `
+INSERT INTO satellites (namespace, name, version, description, manifest)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (namespace, name, version) DO UPDATE
+SET description = EXCLUDED.description, manifest = EXCLUDED.manifest, registered_at = now()`
+
+	manifest, err := json.Marshal(r.Abilities)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, query, namespace, r.Info.Name, r.Info.Version, r.Info.Description, manifest)
+	return err
+}
+
+// registrations looks up the registered satellite versions, see satelliteStore.find.
+type registrations interface {
+	find(ctx context.Context, namespace, name, version string) (satellite.Registration, error)
+}
+
+// find returns the registration of the satellite version.
+// It returns sql.ErrNoRows if the version has never been registered.
+func (s satelliteStore) find(ctx context.Context, namespace, name, version string) (satellite.Registration, error) {
+	const query = `
+SELECT description, manifest FROM satellites
+WHERE namespace = $1 AND name = $2 AND version = $3`
+
+	r := satellite.Registration{Info: satellite.Info{Name: name, Version: version}}
+	var manifest []byte
+	err := s.db.QueryRowContext(ctx, query, namespace, name, version).Scan(&r.Info.Description, &manifest)
+	if err != nil {
+		return r, err
+	}
+
+	err = json.Unmarshal(manifest, &r.Abilities)
+	return r, err
+}
+
+// saveMission creates or updates the mission.
+func (s satelliteStore) saveMission(ctx context.Context, namespace string, m mission) error {
+	const query = `
+INSERT INTO missions (namespace, id, name, steps)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (namespace, id) DO UPDATE
+SET name = EXCLUDED.name, steps = EXCLUDED.steps, updated_at = now()`
+
+	steps, err := json.Marshal(m.Steps)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.ExecContext(ctx, query, namespace, m.ID, m.Name, steps)
+	return err
+}
+
+// missionsUsing returns the missions having a step that uses any version of the satellite.
+func (s satelliteStore) missionsUsing(ctx context.Context, namespace, satellite string) ([]mission, error) {
+	const query = `
+SELECT id, name, steps FROM missions
+WHERE namespace = $1 AND steps @> $2
+ORDER BY id`
+
+	filter, err := json.Marshal([]map[string]string{{"Satellite": satellite}})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, namespace, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missions []mission
+	for rows.Next() {
+		var m mission
+		var steps []byte
+		err = rows.Scan(&m.ID, &m.Name, &steps)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(steps, &m.Steps)
+		if err != nil {
+			return nil, err
+		}
+		missions = append(missions, m)
+	}
+	return missions, rows.Err()
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// registerResponse confirms the registration and lists the missions that would break
+// if their steps were upgraded to the registered version of the satellite.
+// It embeds satellite.Info, so that it is decoded by the satellites as Info.
+type registerResponse struct {
+	satellite.Info
+	BrokenMissions []brokenMission `json:",omitempty"`
+}
+
+// brokenMission lists the incompatibilities between the mission's steps and the new satellite version.
+type brokenMission struct {
+	ID     string
+	Name   string
+	Errors schema.ValidationErrors
+}
+
+// brokenMissions checks the missions using other versions of the registered satellite
+// against its new manifest:
+//   - the ability of a step must still exist;
+//   - the config of a step must match the new config schema;
+//   - the new input schema must accept the messages accepted by the old one;
+//   - the new output schema must not break the fields the next steps read, see readFields
+//     and schema.Changes.Breaking.
+func brokenMissions(
+	ctx context.Context,
+	store satelliteStore,
+	namespace string,
+	r satellite.Registration,
+) ([]brokenMission, error) {
+	missions, err := store.missionsUsing(ctx, namespace, r.Info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var broken []brokenMission
+	for _, m := range missions {
+		var errs schema.ValidationErrors
+		for i, st := range m.Steps {
+			if st.Satellite != r.Info.Name || st.Version == r.Info.Version {
+				continue
+			}
+
+			old, err := findAbility(ctx, store, namespace, st)
+			if err == errUnknownStep {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			read, err := readFields(ctx, store, namespace, m.Steps[i+1:])
+			if err != nil {
+				return nil, err
+			}
+			errs = append(errs, upgradeErrors(old, r, st, read).Prefix(fmt.Sprintf("Steps[%d]", i))...)
+		}
+
+		if len(errs) > 0 {
+			broken = append(broken, brokenMission{ID: m.ID, Name: m.Name, Errors: errs})
+		}
+	}
+	return broken, nil
+}
+
+// upgradeErrors returns the reasons the step would break if it used the new version of the satellite.
+// Read lists the output fields the next steps read, nil means that every output field may be read.
+func upgradeErrors(
+	old satellite.AbilityManifest,
+	r satellite.Registration,
+	st step,
+	read map[string]bool,
+) schema.ValidationErrors {
+	var ability *satellite.AbilityManifest
+	for i, a := range r.Abilities {
+		if a.Kind == old.Kind && a.Name == old.Name {
+			ability = &r.Abilities[i]
+			break
+		}
+	}
+	if ability == nil {
+		return schema.ValidationErrors{{Message: "ability is removed in version " + r.Info.Version}}
+	}
+
+	var errs schema.ValidationErrors
+	err := schema.ValidateJSON(ability.Config, st.Config)
+	if err != nil {
+		errs = append(errs, err.(schema.ValidationErrors).Prefix("Config")...)
+	}
+
+	for _, ch := range schema.Diff(old.Input, ability.Input) {
+		if !ch.Backward {
+			errs = append(errs, changeError(ch, "Input", r.Info.Version))
+		}
+	}
+
+	for _, ch := range schema.Diff(old.Output, ability.Output).Breaking() {
+		if read == nil || readsPath(read, ch.Path) {
+			errs = append(errs, changeError(ch, "Output", r.Info.Version))
+		}
+	}
+	return errs
+}
+
+// readFields returns the paths of the message fields the steps read, i.e. the Input of their abilities.
+// A filter without Output passes the messages it receives, so the steps after it read them too.
+// Nothing reads the messages of the last step.
+//
+// It returns nil if the read fields are unknown, e.g. the ability of a step is not registered
+// or does not describe its Input. Such a step may read any field, e.g. in a template,
+// so every breaking change of the messages is reported.
+func readFields(
+	ctx context.Context,
+	store registrations,
+	namespace string,
+	steps []step,
+) (map[string]bool, error) {
+	read := make(map[string]bool)
+	for _, st := range steps {
+		a, err := findAbility(ctx, store, namespace, st)
+		if err == errUnknownStep {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if a.Input == nil {
+			return nil, nil
+		}
+
+		addPaths(read, a.Input, "")
+		if a.Kind != satellite.KindFilter || a.Output != nil {
+			break
+		}
+	}
+	return read, nil
+}
+
+// addPaths adds the paths of the fields, addressed like schema.Change.Path. An object that
+// describes its fields is read only through them, otherwise the whole value is read.
+func addPaths(paths map[string]bool, f schema.Fields, parent string) {
+	for key, field := range f {
+		p := key
+		if parent != "" {
+			p = parent + "." + key
+		}
+
+		switch {
+		case field.Type.Name == schema.Object.Name && len(field.Fields) > 0:
+			addPaths(paths, field.Fields, p)
+		case field.Type.Name == schema.Collection.Name && field.Elem() != nil &&
+			field.Elem().Type.Name == schema.Object.Name && len(field.Elem().Fields) > 0:
+			addPaths(paths, field.Elem().Fields, p+"[]")
+		default:
+			paths[p] = true
+		}
+	}
+}
+
+// readsPath reports whether any of the read fields is the field at the path,
+// is nested in it or contains it.
+func readsPath(read map[string]bool, path string) bool {
+	for r := range read {
+		if r == path || nested(r, path) || nested(path, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// nested reports whether the path addresses a field nested in the parent.
+func nested(path, parent string) bool {
+	return strings.HasPrefix(path, parent+".") || strings.HasPrefix(path, parent+"[]")
+}
+
+func changeError(ch schema.Change, prefix, version string) schema.ValidationError {
+	msg := fmt.Sprintf("is %s in version %s", ch.Kind, version)
+	if ch.Kind == schema.FieldRetyped {
+		msg = fmt.Sprintf("is retyped from %s to %s in version %s", ch.Old.Type.Name, ch.New.Type.Name, version)
+	}
+	return schema.ValidationError{Path: prefix + "." + ch.Path, Message: msg}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// memRegistrations implements registrations, the registrations are keyed by "name@version".
+type memRegistrations map[string]satellite.Registration
+
+func (m memRegistrations) find(ctx context.Context, namespace, name, version string) (satellite.Registration, error) {
+	r, ok := m[name+"@"+version]
+	if !ok {
+		return r, sql.ErrNoRows
+	}
+	return r, nil
+}
+
+func (m memRegistrations) add(name, version string, abilities ...satellite.AbilityManifest) {
+	m[name+"@"+version] = satellite.Registration{
+		Info:      satellite.Info{Name: name, Version: version},
+		Abilities: abilities,
+	}
+}
+
+var issue = schema.Fields{
+	"title": {Name: "Title", Type: schema.String, Required: true},
+	"issue": {Name: "Issue", Type: schema.Object, Required: true, Fields: schema.Fields{
+		"key":    {Name: "Key", Type: schema.String, Required: true},
+		"labels": {Name: "Labels", Type: schema.Collection, Items: &schema.Field{Type: schema.String}},
+	}},
+}
+
+func TestUpgradeErrors(t *testing.T) {
+	store := memRegistrations{}
+	old := satellite.AbilityManifest{Kind: satellite.KindTrigger, Name: "created", Output: issue}
+	store.add("jira", "1", old)
+
+	// Version 2 drops issue.key and retypes the labels.
+	r := satellite.Registration{
+		Info: satellite.Info{Name: "jira", Version: "2"},
+		Abilities: []satellite.AbilityManifest{{Kind: satellite.KindTrigger, Name: "created", Output: schema.Fields{
+			"title": issue["title"],
+			"issue": {Name: "Issue", Type: schema.Object, Required: true, Fields: schema.Fields{
+				"labels": {Name: "Labels", Type: schema.String},
+			}},
+		}}},
+	}
+	removed := schema.ValidationError{Path: "Output.issue.key", Message: "is removed in version 2"}
+	retyped := schema.ValidationError{
+		Path:    "Output.issue.labels",
+		Message: "is retyped from collection to string in version 2",
+	}
+
+	reads := func(input schema.Fields) satellite.AbilityManifest {
+		return satellite.AbilityManifest{Kind: satellite.KindAction, Name: "notify", Input: input}
+	}
+	store.add("mail", "1", reads(schema.Fields{"title": issue["title"]}))
+	store.add("slack", "1", reads(schema.Fields{"issue": issue["issue"]}))
+	store.add("chat", "1", reads(schema.Fields{"issue": {Name: "Issue", Type: schema.Object}}))
+	store.add("sms", "1", reads(schema.Fields{"issue": {Name: "Issue", Type: schema.Object, Fields: schema.Fields{
+		"key": {Name: "Key", Type: schema.String},
+	}}}))
+	store.add("webhook", "1", reads(nil))
+	store.add("labeled", "1", satellite.AbilityManifest{
+		Kind: satellite.KindFilter,
+		Name: "notify",
+		Input: schema.Fields{"issue": {Name: "Issue", Type: schema.Object, Fields: schema.Fields{
+			"labels": {Name: "Labels", Type: schema.Collection},
+		}}},
+	})
+	store.add("count", "1", satellite.AbilityManifest{
+		Kind:   satellite.KindFilter,
+		Name:   "notify",
+		Input:  schema.Fields{"title": issue["title"]},
+		Output: schema.Fields{"count": {Name: "Count", Type: schema.Integer}},
+	})
+
+	next := func(name string) step {
+		st := step{Satellite: name, Version: "1", Ability: "notify"}
+		if r, ok := store[name+"@1"]; ok {
+			st.Kind = r.Abilities[0].Kind
+		}
+		return st
+	}
+	tests := []struct {
+		name string
+		next []step
+		want schema.ValidationErrors
+	}{
+		{"last step", nil, nil},
+		{"unrelated field", []step{next("mail")}, nil},
+		{"whole object", []step{next("slack")}, schema.ValidationErrors{removed, retyped}},
+		{"object without fields", []step{next("chat")}, schema.ValidationErrors{removed, retyped}},
+		{"nested field", []step{next("sms")}, schema.ValidationErrors{removed}},
+		{"unknown input", []step{next("webhook")}, schema.ValidationErrors{removed, retyped}},
+		{"unknown step", []step{next("pager")}, schema.ValidationErrors{removed, retyped}},
+		{"filter", []step{next("labeled")}, schema.ValidationErrors{retyped}},
+		{"filter passthrough", []step{next("labeled"), next("sms")}, schema.ValidationErrors{removed, retyped}},
+		{"filter with output", []step{next("count"), next("sms")}, nil},
+	}
+	for _, tt := range tests {
+		read, err := readFields(context.Background(), store, "", tt.next)
+		if err != nil {
+			t.Fatal(err)
+		}
+		st := step{Satellite: "jira", Version: "1", Kind: satellite.KindTrigger, Ability: "created"}
+		got := upgradeErrors(old, r, st, read)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUpgradeErrorsRemovedAbility(t *testing.T) {
+	old := satellite.AbilityManifest{Kind: satellite.KindAction, Name: "send"}
+	r := satellite.Registration{Info: satellite.Info{Name: "mail", Version: "2"}}
+
+	got := upgradeErrors(old, r, step{Satellite: "mail", Version: "1", Ability: "send"}, nil)
+	want := schema.ValidationErrors{{Message: "ability is removed in version 2"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
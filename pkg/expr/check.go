@@ -0,0 +1,336 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Kind is a kind of values.
+type Kind int
+
+// Kinds of values.
+const (
+	// Any is the kind of the values that are not known until the expression is evaluated.
+	Any Kind = iota
+	Null
+	Bool
+	Number
+	String
+	Time
+	Duration
+	List
+	Object
+)
+
+var kindNames = [...]string{"any", "null", "boolean", "number", "string", "time", "duration", "list", "object"}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Type is the type of an expression.
+type Type struct {
+	Kind Kind
+	// Elem is the kind of the items of a List.
+	Elem Kind
+	// Integer is true if a Number is always whole, e.g. the sum of integer fields.
+	Integer bool
+}
+
+func (t Type) String() string {
+	if t.Kind == List && t.Elem != Any {
+		return "list of " + t.Elem.String()
+	}
+	return t.Kind.String()
+}
+
+// TypeOf returns the type of the values of the field. A nil field is of Any type.
+func TypeOf(f *schema.Field) Type {
+	if f == nil {
+		return Type{Kind: Any}
+	}
+	switch f.Type {
+	case schema.Boolean:
+		return Type{Kind: Bool}
+	case schema.Integer:
+		return Type{Kind: Number, Integer: true}
+	case schema.Float:
+		return Type{Kind: Number}
+	case schema.String:
+		return Type{Kind: String}
+	case schema.Date, schema.Datetime:
+		return Type{Kind: Time}
+	case schema.Object:
+		return Type{Kind: Object}
+	case schema.Collection:
+		return Type{Kind: List, Elem: TypeOf(f.Elem()).Kind}
+	}
+	return Type{Kind: Any}
+}
+
+// Lookup returns the field at the path, e.g. "issue.type" or "items[0].name".
+// The field is nil, if it is within an object, the fields of which are not described.
+// Lookup reports whether the path may be present in the messages described by the fields.
+func Lookup(fields schema.Fields, path string) (*schema.Field, bool) {
+	var f *schema.Field
+	for i, part := range strings.Split(path, ".") {
+		key := part
+		indices := 0
+		if j := strings.Index(part, "["); j >= 0 {
+			key = part[:j]
+			indices = strings.Count(part[j:], "[")
+		}
+
+		if i > 0 {
+			if f.Type != schema.Object {
+				return nil, false
+			}
+			if len(f.Fields) == 0 {
+				return nil, true
+			}
+			fields = f.Fields
+		}
+		var ok bool
+		f, ok = fields[key]
+		if !ok {
+			return nil, false
+		}
+
+		for ; indices > 0; indices-- {
+			if f.Type != schema.Collection {
+				return nil, false
+			}
+			f = f.Elem()
+			if f == nil {
+				return nil, true
+			}
+		}
+	}
+	return f, true
+}
+
+type checker struct {
+	fields schema.Fields
+}
+
+func (c checker) errorf(n node, format string, args ...interface{}) error {
+	return &Error{Pos: n.position(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func (c checker) check(n node) (Type, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		f, ok := n.value.(float64)
+		return Type{Kind: kindOf(n.value), Integer: ok && f == math.Trunc(f)}, nil
+
+	case *fieldNode:
+		if c.fields == nil {
+			return Type{Kind: Any}, nil
+		}
+		f, ok := Lookup(c.fields, n.path)
+		if !ok {
+			return Type{}, c.errorf(n, "unknown field {{%s}}", n.path)
+		}
+		return TypeOf(f), nil
+
+	case *listNode:
+		t := Type{Kind: List}
+		for i, item := range n.items {
+			it, err := c.check(item)
+			if err != nil {
+				return Type{}, err
+			}
+			if i == 0 {
+				t.Elem = it.Kind
+			} else if it.Kind != t.Elem {
+				t.Elem = Any
+			}
+		}
+		return t, nil
+
+	case *unaryNode:
+		x, err := c.check(n.x)
+		if err != nil {
+			return Type{}, err
+		}
+		if n.op == "not" {
+			if !x.is(Bool) {
+				return Type{}, c.errorf(n, "not expects a boolean, got %s", x)
+			}
+			return Type{Kind: Bool}, nil
+		}
+		if !x.is(Number, Duration) {
+			return Type{}, c.errorf(n, "- expects a number or a duration, got %s", x)
+		}
+		return x, nil
+
+	case *binaryNode:
+		return c.binary(n)
+
+	case *callNode:
+		return c.call(n)
+	}
+	return Type{}, c.errorf(n, "unknown expression")
+}
+
+func (c checker) binary(n *binaryNode) (Type, error) {
+	l, err := c.check(n.l)
+	if err != nil {
+		return Type{}, err
+	}
+	r, err := c.check(n.r)
+	if err != nil {
+		return Type{}, err
+	}
+	mismatch := func() (Type, error) {
+		return Type{}, c.errorf(n, "%s cannot be applied to %s and %s", n.op, l, r)
+	}
+	boolean := Type{Kind: Bool}
+
+	switch n.op {
+	case "and", "or":
+		if !l.is(Bool) || !r.is(Bool) {
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "==", "!=":
+		if !comparable(l.Kind, r.Kind) {
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "<", "<=", ">", ">=":
+		if !ordered(l.Kind, r.Kind) {
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "in", "not in":
+		switch {
+		case r.Kind == Any:
+		case r.Kind == List && comparable(l.Kind, r.Elem):
+		case r.Kind == Object && l.is(String):
+		default:
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "contains":
+		switch {
+		case l.Kind == Any:
+		case l.Kind == List && comparable(l.Elem, r.Kind):
+		case l.Kind == String && r.is(String):
+		default:
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "startsWith", "endsWith", "matches":
+		if !l.is(String) || !r.is(String) {
+			return mismatch()
+		}
+		return boolean, nil
+
+	case "+", "-":
+		t, ok := additive(n.op, l.Kind, r.Kind)
+		if !ok {
+			return mismatch()
+		}
+		return Type{Kind: t, Integer: t == Number && l.Integer && r.Integer}, nil
+
+	case "*", "/", "%":
+		if !l.is(Number) || !r.is(Number) {
+			return mismatch()
+		}
+		// The quotient of integers may be fractional.
+		return Type{Kind: Number, Integer: n.op != "/" && l.Integer && r.Integer}, nil
+	}
+	return mismatch()
+}
+
+func (c checker) call(n *callNode) (Type, error) {
+	fn := functions[n.name]
+	if len(n.args) != len(fn.args) {
+		return Type{}, c.errorf(n, "%s expects %d arguments, got %d", n.name, len(fn.args), len(n.args))
+	}
+	for i, arg := range n.args {
+		t, err := c.check(arg)
+		if err != nil {
+			return Type{}, err
+		}
+		if !t.is(fn.args[i]...) {
+			return Type{}, c.errorf(arg, "%s cannot be applied to %s", n.name, t)
+		}
+		// Constant arguments are checked now rather than when the expression is evaluated.
+		if lit, ok := arg.(*literalNode); ok && fn.parse != nil {
+			if _, err := fn.parse(lit.value); err != nil {
+				return Type{}, c.errorf(arg, "%v", err)
+			}
+		}
+	}
+	return fn.result, nil
+}
+
+// is reports whether the values of the type may be of one of the kinds.
+func (t Type) is(kinds ...Kind) bool {
+	if t.Kind == Any || t.Kind == Null {
+		return true
+	}
+	for _, k := range kinds {
+		if t.Kind == k {
+			return true
+		}
+	}
+	return false
+}
+
+// comparable reports whether the values of the kinds may be equal.
+func comparable(l, r Kind) bool {
+	switch {
+	case l == Any || r == Any || l == Null || r == Null || l == r:
+		return true
+	case l == Time && r == String || l == String && r == Time:
+		return true
+	}
+	return false
+}
+
+// ordered reports whether the values of the kinds are ordered.
+func ordered(l, r Kind) bool {
+	if !comparable(l, r) {
+		return false
+	}
+	for _, k := range []Kind{l, r} {
+		switch k {
+		case Any, Null, Number, String, Time, Duration:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// additive returns the kind of the sum or the difference of the kinds.
+func additive(op string, l, r Kind) (Kind, bool) {
+	switch {
+	case l == Any || r == Any || l == Null || r == Null:
+		return Any, true
+	case l == Number && r == Number:
+		return Number, true
+	case l == Duration && r == Duration:
+		return Duration, true
+	case l == Time && r == Duration:
+		return Time, true
+	case op == "+" && l == String && r == String:
+		return String, true
+	case op == "+" && l == Duration && r == Time:
+		return Time, true
+	case op == "-" && l == Time && r == Time:
+		return Duration, true
+	}
+	return Any, false
+}
@@ -0,0 +1,607 @@
+package expr
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// function is a built-in function.
+type function struct {
+	// args lists the kinds of the arguments.
+	args   [][]Kind
+	result Type
+	call   func(args []interface{}) (interface{}, error)
+	// parse converts a constant argument, so that it can be checked in advance.
+	parse func(v interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"len": {
+		args:   [][]Kind{{String, List, Object}},
+		result: Type{Kind: Number, Integer: true},
+		call: func(args []interface{}) (interface{}, error) {
+			switch v := args[0].(type) {
+			case nil:
+				return nil, nil
+			case string:
+				return float64(len([]rune(v))), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			}
+			return nil, fmt.Errorf("len cannot be applied to %s", kindOf(args[0]))
+		},
+	},
+	"lower": stringFunction(strings.ToLower),
+	"upper": stringFunction(strings.ToUpper),
+	"trim":  stringFunction(strings.TrimSpace),
+	"now": {
+		result: Type{Kind: Time},
+		call: func(args []interface{}) (interface{}, error) {
+			return time.Now(), nil
+		},
+	},
+	"today": {
+		result: Type{Kind: Time},
+		call: func(args []interface{}) (interface{}, error) {
+			return time.Now().UTC().Truncate(24 * time.Hour), nil
+		},
+	},
+	"date": {
+		args:   [][]Kind{{String, Time}},
+		result: Type{Kind: Time},
+		call: func(args []interface{}) (interface{}, error) {
+			return parseTime(args[0])
+		},
+		parse: parseTime,
+	},
+	"duration": {
+		args:   [][]Kind{{String, Duration}},
+		result: Type{Kind: Duration},
+		call: func(args []interface{}) (interface{}, error) {
+			return parseDuration(args[0])
+		},
+		parse: parseDuration,
+	},
+}
+
+func stringFunction(fn func(string) string) function {
+	return function{
+		args:   [][]Kind{{String}},
+		result: Type{Kind: String},
+		call: func(args []interface{}) (interface{}, error) {
+			switch v := args[0].(type) {
+			case nil:
+				return nil, nil
+			case string:
+				return fn(v), nil
+			}
+			return nil, fmt.Errorf("expected a string, got %s", kindOf(args[0]))
+		},
+	}
+}
+
+// parseTime parses a date, e.g. 2017-12-31, or a date and time in RFC 3339.
+func parseTime(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(schema.DatetimeLayout, v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+		if t, err := time.Parse(schema.DateLayout, v); err == nil {
+			return t, nil
+		}
+		return nil, fmt.Errorf("invalid date %q", v)
+	}
+	return nil, fmt.Errorf("expected a date, got %s", kindOf(v))
+}
+
+func parseDuration(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, time.Duration:
+		return v, nil
+	case string:
+		return ParseDuration(v)
+	}
+	return nil, fmt.Errorf("expected a duration, got %s", kindOf(v))
+}
+
+// ParseDuration parses a Go duration, e.g. 1h30m, or days, e.g. 7d or -7d.
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func errorf(n node, format string, args ...interface{}) error {
+	return &Error{Pos: n.position(), Msg: fmt.Sprintf(format, args...)}
+}
+
+func eval(n node, g Getter) (interface{}, error) {
+	switch n := n.(type) {
+	case *literalNode:
+		return n.value, nil
+
+	case *fieldNode:
+		v, _ := g.Get(n.path)
+		return normalize(v), nil
+
+	case *listNode:
+		items := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			v, err := eval(item, g)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = v
+		}
+		return items, nil
+
+	case *unaryNode:
+		x, err := eval(n.x, g)
+		if err != nil {
+			return nil, err
+		}
+		switch x := x.(type) {
+		case nil:
+			if n.op == "not" {
+				return true, nil
+			}
+			return nil, nil
+		case bool:
+			if n.op == "not" {
+				return !x, nil
+			}
+		case float64:
+			if n.op == "-" {
+				return -x, nil
+			}
+		case time.Duration:
+			if n.op == "-" {
+				return -x, nil
+			}
+		}
+		return nil, errorf(n, "%s cannot be applied to %s", n.op, kindOf(x))
+
+	case *binaryNode:
+		return evalBinary(n, g)
+
+	case *callNode:
+		args := make([]interface{}, len(n.args))
+		for i, arg := range n.args {
+			v, err := eval(arg, g)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = v
+		}
+		v, err := functions[n.name].call(args)
+		if err != nil {
+			return nil, errorf(n, "%v", err)
+		}
+		return v, nil
+	}
+	return nil, errorf(n, "unknown expression")
+}
+
+func evalBinary(n *binaryNode, g Getter) (interface{}, error) {
+	l, err := eval(n.l, g)
+	if err != nil {
+		return nil, err
+	}
+
+	// and and or do not evaluate the right operand if the left one decides.
+	if n.op == "and" || n.op == "or" {
+		lb, ok := truth(l)
+		if !ok {
+			return nil, errorf(n, "%s cannot be applied to %s", n.op, kindOf(l))
+		}
+		if lb == (n.op == "or") {
+			return lb, nil
+		}
+		r, err := eval(n.r, g)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := truth(r)
+		if !ok {
+			return nil, errorf(n, "%s cannot be applied to %s", n.op, kindOf(r))
+		}
+		return rb, nil
+	}
+
+	r, err := eval(n.r, g)
+	if err != nil {
+		return nil, err
+	}
+	mismatch := func() (interface{}, error) {
+		return nil, errorf(n, "%s cannot be applied to %s and %s", n.op, kindOf(l), kindOf(r))
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+
+	case "<", "<=", ">", ">=":
+		if l == nil || r == nil {
+			return false, nil
+		}
+		c, ok := compare(l, r)
+		if !ok {
+			return mismatch()
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+
+	case "in", "not in":
+		in, ok := contains(r, l)
+		if !ok {
+			return mismatch()
+		}
+		return in == (n.op == "in"), nil
+
+	case "contains":
+		in, ok := contains(l, r)
+		if !ok {
+			return mismatch()
+		}
+		return in, nil
+
+	case "startsWith", "endsWith", "matches":
+		if l == nil || r == nil {
+			return false, nil
+		}
+		ls, lok := l.(string)
+		rs, rok := r.(string)
+		if !lok || !rok {
+			return mismatch()
+		}
+		switch n.op {
+		case "startsWith":
+			return strings.HasPrefix(ls, rs), nil
+		case "endsWith":
+			return strings.HasSuffix(ls, rs), nil
+		}
+		re := n.re
+		if re == nil {
+			re, err = regexp.Compile(rs)
+			if err != nil {
+				return nil, errorf(n.r, "invalid pattern: %v", err)
+			}
+		}
+		return re.MatchString(ls), nil
+
+	case "+", "-", "*", "/", "%":
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		v, ok := arithmetic(n.op, l, r)
+		if !ok {
+			return mismatch()
+		}
+		return v, nil
+	}
+	return mismatch()
+}
+
+// truth converts a boolean operand, null is false.
+func truth(v interface{}) (bool, bool) {
+	switch v := v.(type) {
+	case nil:
+		return false, true
+	case bool:
+		return v, true
+	}
+	return false, false
+}
+
+func equal(l, r interface{}) bool {
+	if c, ok := compare(l, r); ok {
+		return c == 0
+	}
+	switch l := l.(type) {
+	case []interface{}:
+		rl, ok := r.([]interface{})
+		if !ok || len(l) != len(rl) {
+			return false
+		}
+		for i := range l {
+			if !equal(l[i], rl[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// compare orders the values, it reports false if they are not ordered.
+func compare(l, r interface{}) (int, bool) {
+	l, r = timeOperands(l, r)
+	switch lv := l.(type) {
+	case float64:
+		if rv, ok := r.(float64); ok {
+			return sign(lv - rv), true
+		}
+	case string:
+		switch rv := r.(type) {
+		case string:
+			return strings.Compare(lv, rv), true
+		case time.Time:
+			return compareTimes(lv, rv, false)
+		}
+	case time.Time:
+		return compareTimes(r, lv, true)
+	case time.Duration:
+		if rv, ok := r.(time.Duration); ok {
+			return sign(float64(lv - rv)), true
+		}
+	case bool:
+		if rv, ok := r.(bool); ok && lv == rv {
+			return 0, true
+		}
+	}
+	return 0, false
+}
+
+// compareTimes compares the time with the other value, which is a time or a string.
+// If reverse is false, the other value is the left operand.
+func compareTimes(other interface{}, t time.Time, reverse bool) (int, bool) {
+	o, err := parseTime(other)
+	if err != nil {
+		return 0, false
+	}
+	ot := o.(time.Time)
+	c := 0
+	switch {
+	case ot.Before(t):
+		c = -1
+	case ot.After(t):
+		c = 1
+	}
+	if reverse {
+		c = -c
+	}
+	return c, true
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// contains reports whether the list contains the item, the object has the key
+// or the string contains the substring. It reports false if the operands do not fit.
+func contains(collection, item interface{}) (bool, bool) {
+	switch c := collection.(type) {
+	case nil:
+		return false, true
+	case []interface{}:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, true
+			}
+		}
+		return false, true
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if !ok {
+			return false, item == nil
+		}
+		_, in := c[key]
+		return in, true
+	case string:
+		sub, ok := item.(string)
+		if !ok {
+			return false, item == nil
+		}
+		return strings.Contains(c, sub), true
+	}
+	return false, false
+}
+
+func arithmetic(op string, l, r interface{}) (interface{}, bool) {
+	// Concatenated strings are not parsed, even if they are dates.
+	if _, ok := l.(string); !ok || op != "+" || kindOf(r) != String {
+		l, r = timeOperands(l, r)
+	}
+	switch lv := l.(type) {
+	case float64:
+		rv, ok := r.(float64)
+		if !ok {
+			return nil, false
+		}
+		switch op {
+		case "+":
+			return lv + rv, true
+		case "-":
+			return lv - rv, true
+		case "*":
+			return lv * rv, true
+		case "/":
+			return lv / rv, true
+		}
+		return math.Mod(lv, rv), true
+
+	case string:
+		rv, ok := r.(string)
+		if !ok || op != "+" {
+			return nil, false
+		}
+		return lv + rv, true
+
+	case time.Time:
+		switch rv := r.(type) {
+		case time.Duration:
+			switch op {
+			case "+":
+				return lv.Add(rv), true
+			case "-":
+				return lv.Add(-rv), true
+			}
+		case time.Time:
+			if op == "-" {
+				return lv.Sub(rv), true
+			}
+		}
+
+	case time.Duration:
+		switch rv := r.(type) {
+		case time.Duration:
+			switch op {
+			case "+":
+				return lv + rv, true
+			case "-":
+				return lv - rv, true
+			}
+		case time.Time:
+			if op == "+" {
+				return rv.Add(lv), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// timeOperands parses the strings operated with times or durations and the pairs of strings,
+// which are both times. Date and Datetime fields are strings in the messages,
+// e.g. {{created}} - {{updated}} subtracts the times rather than fails.
+func timeOperands(l, r interface{}) (interface{}, interface{}) {
+	lt, lok := asTime(l)
+	rt, rok := asTime(r)
+	switch {
+	case lok && rok:
+		return lt, rt
+	case lok && kindOf(r) == Duration:
+		return lt, r
+	case rok && kindOf(l) == Duration:
+		return l, rt
+	}
+	return l, r
+}
+
+// asTime parses the time or the string, which is a date or a date and time in RFC 3339.
+func asTime(v interface{}) (time.Time, bool) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := parseTime(v)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return t.(time.Time), true
+	}
+	return time.Time{}, false
+}
+
+// normalize converts a field value to one of the values returned by Eval.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, float64, string, time.Time, time.Duration, []interface{}, map[string]interface{}:
+		return v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = normalize(rv.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		m := make(map[string]interface{}, rv.Len())
+		for _, k := range rv.MapKeys() {
+			m[k.String()] = rv.MapIndex(k).Interface()
+		}
+		return m
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	}
+	return v
+}
+
+// kindOf returns the kind of a value returned by Eval.
+func kindOf(v interface{}) Kind {
+	switch v.(type) {
+	case nil:
+		return Null
+	case bool:
+		return Bool
+	case float64:
+		return Number
+	case string:
+		return String
+	case time.Time:
+		return Time
+	case time.Duration:
+		return Duration
+	case []interface{}:
+		return List
+	case map[string]interface{}:
+		return Object
+	}
+	return Any
+}
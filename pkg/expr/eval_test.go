@@ -0,0 +1,129 @@
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// fields is a getter of the top-level fields.
+type fields map[string]interface{}
+
+func (f fields) Get(path string) (interface{}, bool) {
+	v, ok := f[path]
+	return v, ok
+}
+
+func TestEvalTimeFields(t *testing.T) {
+	schemaFields := schema.Fields{
+		"a":    {Name: "A", Type: schema.Datetime},
+		"b":    {Name: "B", Type: schema.Datetime},
+		"day":  {Name: "Day", Type: schema.Date},
+		"name": {Name: "Name", Type: schema.String},
+	}
+	values := fields{
+		"a":    "2020-01-01T10:00:00+02:00",
+		"b":    "2020-01-01T09:00:00Z",
+		"day":  "2020-01-01",
+		"name": "2020-01-01",
+	}
+	a := time.Date(2020, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		src  string
+		kind Kind
+		want interface{}
+	}{
+		{src: `{{a}} - {{b}}`, kind: Duration, want: -time.Hour},
+		{src: `{{b}} - {{day}}`, kind: Duration, want: 9 * time.Hour},
+		{src: `{{a}} + duration("1d")`, kind: Time, want: a.Add(24 * time.Hour)},
+		{src: `duration("1h") + {{a}}`, kind: Time, want: a.Add(time.Hour)},
+		{src: `{{a}} - duration("1h")`, kind: Time, want: a.Add(-time.Hour)},
+		{src: `{{a}} < {{b}}`, kind: Bool, want: true},
+		{src: `{{a}} > {{b}}`, kind: Bool, want: false},
+		{src: `{{a}} == "2020-01-01T08:00:00Z"`, kind: Bool, want: true},
+		{src: `{{a}} >= {{day}}`, kind: Bool, want: true},
+		{src: `{{a}} < date("2020-01-02")`, kind: Bool, want: true},
+		{src: `{{name}} + "T00:00:00Z"`, kind: String, want: "2020-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		typ, err := e.Check(schemaFields)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if typ.Kind != tt.kind {
+			t.Errorf("%s: checked %s, want %s", tt.src, typ.Kind, tt.kind)
+		}
+
+		got, err := e.Eval(values)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if kindOf(got) != tt.kind {
+			t.Errorf("%s: evaluated to %s %v, want %s", tt.src, kindOf(got), got, tt.kind)
+		}
+		if gt, ok := got.(time.Time); ok {
+			if !gt.Equal(tt.want.(time.Time)) {
+				t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+			}
+		} else if got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvalStrings(t *testing.T) {
+	values := fields{"status": "Open", "version": "1.10"}
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{src: `{{status}} < "Resolved"`, want: true},
+		{src: `{{version}} < "1.9"`, want: true},
+		{src: `{{status}} - "O"`},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.src)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.src, err)
+		}
+		got, err := e.Eval(values)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%s = %v, want an error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %v, %v, want %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestLexUnicode(t *testing.T) {
+	e, err := Parse(`{{größe}} in ["groß", "über"] and not false`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := e.EvalBool(fields{"größe": "über"})
+	if err != nil || !got {
+		t.Errorf("got %v, %v", got, err)
+	}
+
+	_, err = Parse(`größe`)
+	if err == nil || err.Error() != "unknown identifier größe, fields are written as {{größe}} at 0" {
+		t.Errorf("got %v", err)
+	}
+	_, err = Parse(`{{a}} € 1`)
+	if err == nil || err.Error() != `unexpected '€' at 6` {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package expr implements the expressions over message fields used by the basic satellites,
+// e.g. {{issue.type}} == "User Story" && {{issue.points}} >= 3.
+//
+// Operands are
+//   - fields, e.g. {{issue.type}} or {{items[0].name}}, absent fields are null;
+//   - strings, "double-quoted" with Go escapes or 'single-quoted' without escapes;
+//   - numbers, e.g. 3 or 2.5, booleans true and false, and null;
+//   - lists, e.g. ["Open", "In Progress"];
+//   - function calls, e.g. len({{labels}}).
+//
+// Operators, from the lowest precedence to the highest, are
+//   - or, ||
+//   - and, &&
+//   - not, !
+//   - ==, !=, <, <=, >, >=, in, not in, contains, startsWith, endsWith, matches (a regular expression)
+//   - +, -
+//   - *, /, %
+//   - unary -
+//
+// Numbers, strings, times and durations are ordered. A time compared with a string
+// parses the string, e.g. {{created}} > date("2017-12-31"). Times and durations are added
+// and subtracted, e.g. {{due}} < now() + duration("72h"). Strings, which are dates or
+// dates and times in RFC 3339, e.g. Date and Datetime fields, are times in the comparisons
+// and the arithmetic with times, durations and other such strings, e.g. {{resolved}} - {{created}}.
+//
+// Functions are
+//   - len(string, list or object) number
+//   - lower(string), upper(string), trim(string) string
+//   - now() time, today() time, the start of the current day in UTC
+//   - date(string) time, parses a date, e.g. 2017-12-31, or a date and time in RFC 3339
+//   - duration(string) duration, parses a Go duration, e.g. 1h30m, or days, e.g. 7d
+package expr
+
+import (
+	"fmt"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Error is an error at the position of the expression source.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at %d", e.Msg, e.Pos)
+}
+
+// Getter resolves the fields of the expressions, e.g. satellite.Message.
+type Getter interface {
+	Get(path string) (interface{}, bool)
+}
+
+// Expression is a parsed expression.
+type Expression struct {
+	src  string
+	root node
+}
+
+// Parse parses the expression.
+func Parse(src string) (*Expression, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{src: src, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Fields returns the paths of the fields referred to by the expression.
+func (e *Expression) Fields() []string {
+	var paths []string
+	walk(e.root, func(n node) {
+		if f, ok := n.(*fieldNode); ok {
+			paths = append(paths, f.path)
+		}
+	})
+	return paths
+}
+
+// Check returns the type of the expression evaluated on the messages described by the fields.
+// It fails if a field is not described or an operator is applied to the values of the wrong types.
+// Nil fields are unknown, then the types of the fields are checked when the expression is evaluated.
+func (e *Expression) Check(fields schema.Fields) (Type, error) {
+	c := checker{fields: fields}
+	return c.check(e.root)
+}
+
+// Eval evaluates the expression with the fields resolved by the getter.
+// The result is nil, bool, float64, string, time.Time, time.Duration,
+// []interface{} or map[string]interface{}.
+func (e *Expression) Eval(g Getter) (interface{}, error) {
+	return eval(e.root, g)
+}
+
+// EvalBool evaluates the expression, which must be a boolean or null. Null is false.
+func (e *Expression) EvalBool(g Getter) (bool, error) {
+	v, err := e.Eval(g)
+	if err != nil {
+		return false, err
+	}
+	switch v := v.(type) {
+	case nil:
+		return false, nil
+	case bool:
+		return v, nil
+	}
+	return false, fmt.Errorf("expression is %s, not boolean", kindOf(v))
+}
@@ -0,0 +1,138 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenField
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// operators lists the operators, the two-character ones first.
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"<", ">", "!", "(", ")", "[", "]", ",", "+", "-", "*", "/", "%",
+}
+
+// lex splits the source into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case strings.HasPrefix(src[i:], "{{"):
+			end := strings.Index(src[i:], "}}")
+			if end < 0 {
+				return nil, &Error{Pos: i, Msg: "unclosed {{"}
+			}
+			path := strings.TrimSpace(src[i+2 : i+end])
+			if path == "" {
+				return nil, &Error{Pos: i, Msg: "empty field path"}
+			}
+			tokens = append(tokens, token{kind: tokenField, text: path, pos: i})
+			i += end + 2
+
+		case c == '"' || c == '\'':
+			t, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: err.Error()}
+			}
+			t.pos = i
+			tokens = append(tokens, t)
+			i += n
+
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(src) && src[i+1] >= '0' && src[i+1] <= '9':
+			j := i
+			for j < len(src) && (isDigit(src[j]) || src[j] == '.' ||
+				(src[j] == 'e' || src[j] == 'E') ||
+				(src[j] == '+' || src[j] == '-') && (src[j-1] == 'e' || src[j-1] == 'E')) {
+				j++
+			}
+			v, err := strconv.ParseFloat(src[i:j], 64)
+			if err != nil {
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("invalid number %q", src[i:j])}
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[i:j], value: v, pos: i})
+			i = j
+
+		case c == '_' || isLetter(src[i:]):
+			j := i
+			for j < len(src) && (src[j] == '_' || isDigit(src[j]) || isLetter(src[j:])) {
+				_, n := utf8.DecodeRuneInString(src[j:])
+				j += n
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[i:j], pos: i})
+			i = j
+
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(src[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(src[i:])
+				return nil, &Error{Pos: i, Msg: fmt.Sprintf("unexpected %q", r)}
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString scans a double-quoted string with Go escapes or a single-quoted string without escapes,
+// e.g. '^\d+$'. It returns the token and the number of the bytes scanned.
+func lexString(src string) (token, int, error) {
+	quote := src[0]
+	for j := 1; j < len(src); j++ {
+		switch {
+		case src[j] == '\\' && quote == '"':
+			j++
+		case src[j] == quote:
+			text := src[:j+1]
+			if quote == '\'' {
+				return token{kind: tokenString, text: text, value: text[1:j]}, j + 1, nil
+			}
+			s, err := strconv.Unquote(text)
+			if err != nil {
+				return token{}, 0, fmt.Errorf("invalid string %s", text)
+			}
+			return token{kind: tokenString, text: text, value: s}, j + 1, nil
+		}
+	}
+	return token{}, 0, fmt.Errorf("unclosed string")
+}
+
+// isLetter reports whether the source starts with a letter, which may take several bytes.
+func isLetter(src string) bool {
+	r, _ := utf8.DecodeRuneInString(src)
+	return unicode.IsLetter(r)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
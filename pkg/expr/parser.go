@@ -0,0 +1,345 @@
+package expr
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type node interface {
+	position() int
+}
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+type fieldNode struct {
+	pos  int
+	path string
+}
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+type unaryNode struct {
+	pos int
+	op  string
+	x   node
+}
+
+type binaryNode struct {
+	pos  int
+	op   string
+	l, r node
+	// re is the compiled pattern of matches, if it is a literal.
+	re *regexp.Regexp
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []node
+}
+
+func (n *literalNode) position() int { return n.pos }
+func (n *fieldNode) position() int   { return n.pos }
+func (n *listNode) position() int    { return n.pos }
+func (n *unaryNode) position() int   { return n.pos }
+func (n *binaryNode) position() int  { return n.pos }
+func (n *callNode) position() int    { return n.pos }
+
+// walk calls fn for the node and its descendants.
+func walk(n node, fn func(node)) {
+	fn(n)
+	switch n := n.(type) {
+	case *listNode:
+		for _, item := range n.items {
+			walk(item, fn)
+		}
+	case *unaryNode:
+		walk(n.x, fn)
+	case *binaryNode:
+		walk(n.l, fn)
+		walk(n.r, fn)
+	case *callNode:
+		for _, arg := range n.args {
+			walk(arg, fn)
+		}
+	}
+}
+
+// Comparison operators. Unlike the others, they do not associate, a < b < c is invalid.
+var comparisons = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"in": true, "not in": true, "contains": true, "startsWith": true, "endsWith": true, "matches": true,
+}
+
+// parser is a recursive descent parser, a method per precedence level.
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokenEOF {
+		p.i++
+	}
+	return t
+}
+
+// is reports whether the next token is the operator or the keyword.
+func (p *parser) is(texts ...string) bool {
+	t := p.peek()
+	if t.kind != tokenOp && t.kind != tokenIdent {
+		return false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.is(text) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return &Error{Pos: t.pos, Msg: "unexpected end of expression"}
+	}
+	return &Error{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s", t.text)}
+}
+
+func (p *parser) parse() (node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("or", "||") {
+		t := p.next()
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{pos: t.pos, op: "or", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("and", "&&") {
+		t := p.next()
+		r, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{pos: t.pos, op: "and", l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) not() (node, error) {
+	if p.is("not", "!") {
+		t := p.next()
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: t.pos, op: "not", x: x}, nil
+	}
+	return p.comparison()
+}
+
+func (p *parser) comparison() (node, error) {
+	l, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	op := t.text
+	if t.kind == tokenOp || t.kind == tokenIdent {
+		if op == "not" && p.tokens[p.i+1].text == "in" {
+			p.next()
+			op = "not in"
+		}
+	}
+	if (t.kind != tokenOp && t.kind != tokenIdent) || !comparisons[op] {
+		return l, nil
+	}
+	p.next()
+
+	r, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	n := &binaryNode{pos: t.pos, op: op, l: l, r: r}
+	if op == "matches" {
+		if lit, ok := r.(*literalNode); ok {
+			pattern, ok := lit.value.(string)
+			if !ok {
+				return nil, &Error{Pos: lit.pos, Msg: "pattern must be a string"}
+			}
+			n.re, err = regexp.Compile(pattern)
+			if err != nil {
+				return nil, &Error{Pos: lit.pos, Msg: "invalid pattern: " + err.Error()}
+			}
+		}
+	}
+
+	if p.is("==", "!=", "<", "<=", ">", ">=", "in", "contains", "startsWith", "endsWith", "matches") {
+		return nil, &Error{Pos: p.peek().pos, Msg: "comparisons cannot be chained"}
+	}
+	return n, nil
+}
+
+func (p *parser) additive() (node, error) {
+	l, err := p.multiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.is("+", "-") {
+		t := p.next()
+		r, err := p.multiplicative()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{pos: t.pos, op: t.text, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) multiplicative() (node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOp && p.is("*", "/", "%") {
+		t := p.next()
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = &binaryNode{pos: t.pos, op: t.text, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek().kind == tokenOp && p.is("-") {
+		t := p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: t.pos, op: "-", x: x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		p.next()
+		return &literalNode{pos: t.pos, value: t.value}, nil
+
+	case tokenField:
+		p.next()
+		return &fieldNode{pos: t.pos, path: t.text}, nil
+
+	case tokenIdent:
+		p.next()
+		switch t.text {
+		case "true":
+			return &literalNode{pos: t.pos, value: true}, nil
+		case "false":
+			return &literalNode{pos: t.pos, value: false}, nil
+		case "null":
+			return &literalNode{pos: t.pos, value: nil}, nil
+		}
+		if _, ok := functions[t.text]; !ok || !p.is("(") {
+			msg := fmt.Sprintf("unknown identifier %s, fields are written as {{%s}}", t.text, t.text)
+			return nil, &Error{Pos: t.pos, Msg: msg}
+		}
+		p.next()
+		args, err := p.list(")")
+		if err != nil {
+			return nil, err
+		}
+		return &callNode{pos: t.pos, name: t.text, args: args}, nil
+
+	case tokenOp:
+		switch t.text {
+		case "(":
+			p.next()
+			n, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return n, p.expect(")")
+		case "[":
+			p.next()
+			items, err := p.list("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{pos: t.pos, items: items}, nil
+		}
+	}
+	return nil, p.unexpected()
+}
+
+// list parses comma-separated expressions up to the closing token.
+func (p *parser) list(end string) ([]node, error) {
+	var items []node
+	if p.is(end) {
+		p.next()
+		return items, nil
+	}
+	for {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, n)
+		if p.is(end) {
+			p.next()
+			return items, nil
+		}
+		err = p.expect(",")
+		if err != nil {
+			return nil, err
+		}
+	}
+}
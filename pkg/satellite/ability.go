@@ -0,0 +1,72 @@
+package satellite
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Message is a message passed between the steps of a mission.
+// Its fields are described by schema.Fields.
+type Message map[string]interface{}
+
+// Emitter publishes the messages produced by a trigger.
+type Emitter interface {
+	Emit(ctx context.Context, m Message) error
+}
+
+// EmitterFunc is an adapter to allow the use of ordinary functions as Emitters.
+type EmitterFunc func(ctx context.Context, m Message) error
+
+// Emit calls f(ctx, m).
+func (f EmitterFunc) Emit(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// TriggerFunc runs a trigger with the decoded config. It publishes messages
+// with the Emitter and blocks until the context is canceled or a fatal error occurs.
+// The satellite restarts a failed trigger with a backoff, see Config.TriggerBackoffInMs.
+type TriggerFunc func(ctx context.Context, config interface{}, e Emitter) error
+
+// ActionFunc executes an action with the decoded config on the incoming message
+// and returns the result of the action.
+type ActionFunc func(ctx context.Context, config interface{}, m Message) (Message, error)
+
+// FilterFunc reports whether the incoming message passes the filter with the decoded config.
+type FilterFunc func(ctx context.Context, config interface{}, m Message) (bool, error)
+
+// ModifierFunc returns the incoming message modified according to the decoded config.
+type ModifierFunc func(ctx context.Context, config interface{}, m Message) (Message, error)
+
+// SplitterFunc splits the incoming message into many according to the decoded config.
+type SplitterFunc func(ctx context.Context, config interface{}, m Message) ([]Message, error)
+
+// ValidatorFunc checks the decoded config beyond its ConfigFields, e.g. that a path exists.
+// It returns schema.ValidationErrors describing the invalid fields of the config.
+type ValidatorFunc func(ctx context.Context, config interface{}) error
+
+// OutputFunc describes the messages the ability outputs with the decoded config
+// when its Output depends on the config, e.g. the fields added by a modifier.
+// Input describes the incoming messages, it is nil if they are unknown.
+// A nil result means the outgoing messages are unknown.
+type OutputFunc func(ctx context.Context, config interface{}, input schema.Fields) (schema.Fields, error)
+
+// DecodeConfig decodes the JSON encoded config into a new value of the same type as prototype,
+// e.g. Trigger.Config. The returned value has the prototype's type, not a pointer to it.
+func DecodeConfig(prototype interface{}, data []byte) (interface{}, error) {
+	if prototype == nil {
+		return nil, nil
+	}
+
+	t := reflect.TypeOf(prototype)
+	v := reflect.New(t)
+	if len(data) > 0 {
+		err := json.Unmarshal(data, v.Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return v.Elem().Interface(), nil
+}
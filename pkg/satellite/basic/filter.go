@@ -0,0 +1,47 @@
+package basic
+
+import (
+	"context"
+
+	"github.com/antonkuzmenko/gogarin/pkg/expr"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+type FilterConfig struct {
+	Condition string `json:"condition" required:"true" examples:"{{issue.points}} >= 3" desc:"Boolean expression."`
+}
+
+// Filter passes the messages satisfying the condition. A condition evaluated to null,
+// e.g. a comparison with an absent field, is not satisfied.
+func Filter(ctx context.Context, config interface{}, m satellite.Message) (bool, error) {
+	e, err := expr.Parse(config.(FilterConfig).Condition)
+	if err != nil {
+		return false, err
+	}
+	return e.EvalBool(m)
+}
+
+// ValidateFilter checks that the condition is a boolean expression.
+// If the incoming messages are known, their fields are type-checked, see expr.Expression.Check.
+func ValidateFilter(ctx context.Context, config interface{}) error {
+	c := config.(FilterConfig)
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+	return validateCondition("condition", c.Condition, input)
+}
+
+// validateCondition checks the boolean expression at the path of the config.
+func validateCondition(path, condition string, input schema.Fields) error {
+	e, err := expr.Parse(condition)
+	if err != nil {
+		return schema.ValidationErrors{{Path: path, Message: err.Error()}}
+	}
+	t, err := e.Check(input)
+	if err != nil {
+		return schema.ValidationErrors{{Path: path, Message: err.Error()}}
+	}
+	if t.Kind != expr.Bool && t.Kind != expr.Any {
+		return schema.ValidationErrors{{Path: path, Message: "must be a boolean expression, not " + t.String()}}
+	}
+	return nil
+}
@@ -0,0 +1,611 @@
+package basic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/expr"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Operations of the modifier.
+const (
+	// OpSet sets the field to the value of the expression, e.g. {{issue.points}} * 2.
+	OpSet = "set"
+	// OpRename moves the field From to the path.
+	OpRename = "rename"
+	// OpDelete removes the field.
+	OpDelete = "delete"
+	// OpCopy copies the field From to the path.
+	OpCopy = "copy"
+	// OpCast converts the field to the Type, e.g. "42" to 42.
+	OpCast = "cast"
+	// OpFormat sets the field to the rendered template, see satellite.Template.
+	OpFormat = "format"
+	// OpAddDuration sets the field to the date of the field From, or of the field itself, plus the Duration.
+	OpAddDuration = "addDuration"
+)
+
+type ModifyConfig struct {
+	Operations []Operation `json:"operations" required:"true" min:"1" desc:"Operations applied in order."`
+}
+
+// Operation changes a field of the message. Paths are keys of nested objects, e.g. issue.summary,
+// the objects are created when a field is written into them.
+type Operation struct {
+	Op         string `json:"op" required:"true" enum:"set,rename,delete,copy,cast,format,addDuration"`
+	Path       string `json:"path" required:"true" examples:"issue.summary" desc:"Path of the changed field."`
+	From       string `json:"from,omitempty" desc:"Path of the source field of rename, copy and addDuration."`
+	Expression string `json:"expression,omitempty" examples:"{{issue.points}} * 2" desc:"Value of set."`
+	Template   string `json:"template,omitempty" examples:"{{issue.key}}: {{issue.summary}}" desc:"Text of format."`
+	Type       string `json:"type,omitempty" enum:"boolean,integer,float,string,date,datetime" desc:"Type of cast."`
+	Duration   string `json:"duration,omitempty" examples:"72h,-7d" desc:"Duration of addDuration."`
+}
+
+// Modify applies the operations to a copy of the message.
+// Operations on absent fields, other than set and format, do nothing.
+func Modify(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	res := satellite.Message(copyValue(map[string]interface{}(m)).(map[string]interface{}))
+	for i, op := range config.(ModifyConfig).Operations {
+		err := apply(res, op)
+		if err != nil {
+			return nil, fmt.Errorf("operations[%d]: %v", i, err)
+		}
+	}
+	return res, nil
+}
+
+func apply(m satellite.Message, op Operation) error {
+	switch op.Op {
+	case OpSet:
+		e, err := expr.Parse(op.Expression)
+		if err != nil {
+			return err
+		}
+		v, err := e.Eval(m)
+		if err != nil {
+			return err
+		}
+		return setPath(m, op.Path, messageValue(v))
+
+	case OpRename, OpCopy:
+		v, ok := m.Get(op.From)
+		if !ok {
+			return nil
+		}
+		if op.Op == OpRename {
+			deletePath(m, op.From)
+		}
+		return setPath(m, op.Path, copyValue(v))
+
+	case OpDelete:
+		deletePath(m, op.Path)
+		return nil
+
+	case OpCast:
+		v, ok := m.Get(op.Path)
+		if !ok || v == nil {
+			return nil
+		}
+		t, _ := schema.TypeByName(op.Type)
+		v, err := cast(v, t)
+		if err != nil {
+			return err
+		}
+		return setPath(m, op.Path, v)
+
+	case OpFormat:
+		s, err := satellite.Template(op.Template).Render(m)
+		if err != nil {
+			return err
+		}
+		return setPath(m, op.Path, s)
+
+	case OpAddDuration:
+		v, ok := m.Get(sourcePath(op))
+		if !ok || v == nil {
+			return nil
+		}
+		d, err := expr.ParseDuration(op.Duration)
+		if err != nil {
+			return err
+		}
+		t, isDate, err := parseTime(v)
+		if err != nil {
+			return err
+		}
+		t = t.Add(d)
+		if isDate && d%(24*time.Hour) == 0 {
+			return setPath(m, op.Path, t.Format(schema.DateLayout))
+		}
+		return setPath(m, op.Path, t.Format(schema.DatetimeLayout))
+	}
+	return fmt.Errorf("unknown operation %q", op.Op)
+}
+
+// sourcePath returns the path of the field addDuration reads.
+func sourcePath(op Operation) string {
+	if op.From == "" {
+		return op.Path
+	}
+	return op.From
+}
+
+// setPath sets the field at the path, creating the missing objects on the way.
+func setPath(m satellite.Message, path string, v interface{}) error {
+	keys := strings.Split(path, ".")
+	obj := map[string]interface{}(m)
+	for i, key := range keys[:len(keys)-1] {
+		child, ok := obj[key]
+		if !ok || child == nil {
+			next := map[string]interface{}{}
+			obj[key] = next
+			obj = next
+			continue
+		}
+		next, ok := child.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+		}
+		obj = next
+	}
+	obj[keys[len(keys)-1]] = v
+	return nil
+}
+
+// deletePath removes the field at the path, if it is present.
+func deletePath(m satellite.Message, path string) {
+	keys := strings.Split(path, ".")
+	obj := map[string]interface{}(m)
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := obj[key].(map[string]interface{})
+		if !ok {
+			return
+		}
+		obj = next
+	}
+	delete(obj, keys[len(keys)-1])
+}
+
+// copyValue returns a deep copy of the objects and collections of a message.
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			c[key] = copyValue(value)
+		}
+		return c
+	case satellite.Message:
+		return copyValue(map[string]interface{}(v))
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, item := range v {
+			c[i] = copyValue(item)
+		}
+		return c
+	}
+	return v
+}
+
+// messageValue converts a value returned by expr.Expression.Eval to a value of a message.
+// Times are formatted as Datetime and durations as Go durations.
+func messageValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return v.Format(schema.DatetimeLayout)
+	case time.Duration:
+		return v.String()
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = messageValue(item)
+		}
+		return items
+	}
+	return copyValue(v)
+}
+
+// cast converts a scalar value to the type. Numbers are cast to dates as Unix time in seconds.
+func cast(v interface{}, t schema.FieldType) (interface{}, error) {
+	n, isNumber := number(v)
+	switch t {
+	case schema.Boolean:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to boolean", v)
+			}
+			return b, nil
+		}
+		if isNumber {
+			return n != 0, nil
+		}
+
+	case schema.Integer, schema.Float:
+		switch v := v.(type) {
+		case bool:
+			if v {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot cast %q to %s", v, t.Name)
+			}
+			n, isNumber = f, true
+		}
+		if isNumber && t == schema.Integer {
+			return math.Trunc(n), nil
+		}
+		if isNumber {
+			return n, nil
+		}
+
+	case schema.String:
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+		default:
+			return satellite.Format(v)
+		}
+
+	case schema.Date, schema.Datetime:
+		var tm time.Time
+		if isNumber {
+			sec, frac := math.Modf(n)
+			tm = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+		} else {
+			var err error
+			tm, _, err = parseTime(v)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if t == schema.Date {
+			return tm.Format(schema.DateLayout), nil
+		}
+		return tm.Format(schema.DatetimeLayout), nil
+	}
+	return nil, fmt.Errorf("cannot cast %s to %s", kindName(v), t.Name)
+}
+
+// number returns the value of a number decoded from JSON.
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// parseTime parses a Date or a Datetime. It reports whether the value is a Date.
+func parseTime(v interface{}) (time.Time, bool, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, false, nil
+	case string:
+		if t, err := time.Parse(schema.DatetimeLayout, v); err == nil {
+			return t, false, nil
+		}
+		if t, err := time.Parse(schema.DateLayout, v); err == nil {
+			return t, true, nil
+		}
+		return time.Time{}, false, fmt.Errorf("invalid date %q", v)
+	}
+	return time.Time{}, false, fmt.Errorf("cannot cast %s to a date", kindName(v))
+}
+
+func kindName(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "collection"
+	}
+	if _, ok := number(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ValidateModify checks the operations. If the incoming messages are known,
+// the fields read by the operations must be present, see DescribeModify.
+func ValidateModify(ctx context.Context, config interface{}) error {
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+	_, err := describeModify(config.(ModifyConfig).Operations, input)
+	return err
+}
+
+// DescribeModify applies the operations to the fields of the incoming messages.
+// The outgoing messages are unknown if the incoming ones are, or if the type of a set expression is not known
+// until it is evaluated, e.g. because it reads a field of an object, the fields of which are not described.
+func DescribeModify(ctx context.Context, config interface{}, input schema.Fields) (schema.Fields, error) {
+	return describeModify(config.(ModifyConfig).Operations, input)
+}
+
+func describeModify(ops []Operation, input schema.Fields) (schema.Fields, error) {
+	var errs schema.ValidationErrors
+	// fields describes the message after the previous operations, it is nil if the message is unknown.
+	fields := copyFields(input)
+	for i, op := range ops {
+		fail := func(key, format string, args ...interface{}) {
+			errs = append(errs, schema.ValidationError{
+				Path:    fmt.Sprintf("operations[%d].%s", i, key),
+				Message: fmt.Sprintf(format, args...),
+			})
+		}
+		// lookup returns the field read by the operation, it is nil if the field is not described.
+		lookup := func(key, path string) (*schema.Field, bool) {
+			if !validPath(path) {
+				fail(key, "must be a dot-separated path of object keys")
+				return nil, false
+			}
+			if fields == nil {
+				return nil, true
+			}
+			f, ok := expr.Lookup(fields, path)
+			if !ok {
+				fail(key, "unknown field %s", path)
+			}
+			return f, ok
+		}
+		// put describes the field written by the operation, nil makes the message unknown.
+		put := func(f *schema.Field) {
+			if fields == nil {
+				return
+			}
+			if f == nil {
+				fields = nil
+				return
+			}
+			err := putField(fields, op.Path, f)
+			if err != nil {
+				fail("path", err.Error())
+			}
+		}
+
+		if !validPath(op.Path) {
+			fail("path", "must be a dot-separated path of object keys")
+			continue
+		}
+
+		switch op.Op {
+		case OpSet:
+			if op.Expression == "" {
+				fail("expression", "is required")
+				continue
+			}
+			e, err := expr.Parse(op.Expression)
+			if err != nil {
+				fail("expression", err.Error())
+				continue
+			}
+			t, err := e.Check(fields)
+			if err != nil {
+				fail("expression", err.Error())
+				continue
+			}
+			f := fieldOf(t)
+			if f != nil {
+				f.Required = true
+				f.Nullable = true
+			}
+			put(f)
+
+		case OpRename, OpCopy:
+			if op.From == "" {
+				fail("from", "is required")
+				continue
+			}
+			f, ok := lookup("from", op.From)
+			if !ok || fields == nil {
+				continue
+			}
+			if op.Op == OpRename {
+				removeField(fields, op.From)
+			}
+			f = copyField(f)
+			if f != nil {
+				f.Name = lastKey(op.Path)
+			}
+			put(f)
+
+		case OpDelete:
+			_, ok := lookup("path", op.Path)
+			if ok && fields != nil {
+				removeField(fields, op.Path)
+			}
+
+		case OpCast:
+			t, ok := schema.TypeByName(op.Type)
+			if !ok || t == schema.Object || t == schema.Collection {
+				fail("type", "must be one of boolean, integer, float, string, date or datetime")
+				continue
+			}
+			f, ok := lookup("path", op.Path)
+			if !ok || fields == nil {
+				continue
+			}
+			if f != nil && (f.Type == schema.Object || f.Type == schema.Collection) {
+				fail("path", "cannot cast %s to %s", f.Type.Name, t.Name)
+				continue
+			}
+			c := &schema.Field{Name: lastKey(op.Path), Type: t}
+			if f != nil {
+				c.Name, c.Description, c.Required, c.Nullable = f.Name, f.Description, f.Required, f.Nullable
+			}
+			put(c)
+
+		case OpFormat:
+			paths, err := satellite.Template(op.Template).Fields()
+			if err != nil {
+				fail("template", err.Error())
+				continue
+			}
+			if fields != nil {
+				for _, p := range paths {
+					if _, ok := expr.Lookup(fields, p); !ok {
+						fail("template", "unknown field {{%s}}", p)
+					}
+				}
+			}
+			put(&schema.Field{Name: lastKey(op.Path), Type: schema.String, Required: true})
+
+		case OpAddDuration:
+			d, err := expr.ParseDuration(op.Duration)
+			if err != nil {
+				fail("duration", err.Error())
+				continue
+			}
+			key := "path"
+			if op.From != "" {
+				key = "from"
+			}
+			f, ok := lookup(key, sourcePath(op))
+			if !ok || fields == nil {
+				continue
+			}
+			c := &schema.Field{Name: lastKey(op.Path), Type: schema.Datetime}
+			if f != nil {
+				if f.Type != schema.Date && f.Type != schema.Datetime && f.Type != schema.String {
+					fail(key, "must be a date, not %s", f.Type.Name)
+					continue
+				}
+				if f.Type == schema.Date && d%(24*time.Hour) == 0 {
+					c.Type = schema.Date
+				}
+				c.Required, c.Nullable = f.Required, f.Nullable
+			}
+			put(c)
+
+		default:
+			fail("op", "unknown operation %q", op.Op)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return fields, nil
+}
+
+// fieldOf describes the values of the type, it returns nil if they are unknown.
+func fieldOf(t expr.Type) *schema.Field {
+	switch t.Kind {
+	case expr.Bool:
+		return &schema.Field{Type: schema.Boolean}
+	case expr.Number:
+		if t.Integer {
+			return &schema.Field{Type: schema.Integer}
+		}
+		return &schema.Field{Type: schema.Float}
+	case expr.String:
+		return &schema.Field{Type: schema.String}
+	case expr.Time:
+		return &schema.Field{Type: schema.Datetime}
+	case expr.Duration:
+		return &schema.Field{Type: schema.String, Format: schema.FormatDuration}
+	case expr.Object:
+		return &schema.Field{Type: schema.Object}
+	case expr.List:
+		return &schema.Field{Type: schema.Collection, Items: fieldOf(expr.Type{Kind: t.Elem})}
+	}
+	return nil
+}
+
+// validPath reports whether the path is a dot-separated path of object keys, e.g. issue.summary.
+func validPath(path string) bool {
+	for _, key := range strings.Split(path, ".") {
+		if key == "" || strings.ContainsAny(key, "[]") {
+			return false
+		}
+	}
+	return true
+}
+
+func lastKey(path string) string {
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// putField describes the field at the path, describing the missing objects on the way.
+func putField(fields schema.Fields, path string, f *schema.Field) error {
+	keys := strings.Split(path, ".")
+	for i, key := range keys[:len(keys)-1] {
+		parent, ok := fields[key]
+		if !ok {
+			parent = &schema.Field{Name: key, Type: schema.Object, Required: true, Fields: schema.Fields{}}
+			fields[key] = parent
+			fields = parent.Fields
+			continue
+		}
+		if parent.Type != schema.Object {
+			return fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+		}
+		if len(parent.Fields) == 0 {
+			// The fields of the object are not described.
+			return nil
+		}
+		fields = parent.Fields
+	}
+	if f.Name == "" {
+		f.Name = keys[len(keys)-1]
+	}
+	fields[keys[len(keys)-1]] = f
+	return nil
+}
+
+// removeField removes the field at the path, if it is described.
+func removeField(fields schema.Fields, path string) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		parent, ok := fields[key]
+		if !ok || parent.Type != schema.Object {
+			return
+		}
+		fields = parent.Fields
+	}
+	delete(fields, keys[len(keys)-1])
+}
+
+// copyFields returns a deep copy of the fields, so that the operations do not change the input.
+func copyFields(fields schema.Fields) schema.Fields {
+	if fields == nil {
+		return nil
+	}
+	c := make(schema.Fields, len(fields))
+	for key, f := range fields {
+		c[key] = copyField(f)
+	}
+	return c
+}
+
+func copyField(f *schema.Field) *schema.Field {
+	if f == nil {
+		return nil
+	}
+	c := *f
+	c.Fields = copyFields(f.Fields)
+	c.Items = copyField(f.Items)
+	return &c
+}
@@ -0,0 +1,33 @@
+package basic
+
+import (
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+func TestDescribeSetNumbers(t *testing.T) {
+	input := schema.Fields{
+		"count":  {Name: "Count", Type: schema.Integer, Required: true},
+		"points": {Name: "Points", Type: schema.Float, Required: true},
+		"labels": {Name: "Labels", Type: schema.Collection, Items: &schema.Field{Type: schema.String}},
+	}
+	tests := map[string]schema.FieldType{
+		"{{count}} + 1":          schema.Integer,
+		"-{{count}} * 2 % 3":     schema.Integer,
+		"len({{labels}}) - 1":    schema.Integer,
+		"{{count}} / 2":          schema.Float,
+		"{{count}} + 0.5":        schema.Float,
+		"{{count}} * {{points}}": schema.Float,
+	}
+	for expression, want := range tests {
+		fields, err := describeModify([]Operation{{Op: OpSet, Path: "result", Expression: expression}}, input)
+		if err != nil {
+			t.Errorf("%s: %v", expression, err)
+			continue
+		}
+		if got := fields["result"].Type; got != want {
+			t.Errorf("%s: got %s, want %s", expression, got.Name, want.Name)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package basic
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "Basic",
+	Version:     "0.1.0-alpha",
+	Description: "Filters, modifies and splits messages by their fields.",
+}
+
+// New returns the satellite with all its abilities.
+func New(conn transport.Connection, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+
+	sat.AddFilter(
+		satellite.Filter{
+			Call: Filter,
+			Info: satellite.AbilityInfo{
+				Name:        "Filter",
+				Description: "Passes the messages satisfying the condition, e.g. {{issue.type}} == \"User Story\".",
+			},
+			Config:    FilterConfig{},
+			Validator: ValidateFilter,
+		},
+	)
+
+	sat.AddModifier(
+		satellite.Modifier{
+			Call: Modify,
+			Info: satellite.AbilityInfo{
+				Name:        "Modify",
+				Description: "Sets, renames, deletes, copies, casts and formats the fields of the messages.",
+			},
+			Config:     ModifyConfig{},
+			Validator:  ValidateModify,
+			OutputFunc: DescribeModify,
+		},
+	)
+
+	sat.AddSplitter(
+		satellite.Splitter{
+			Call: Split,
+			Info: satellite.AbilityInfo{
+				Name:        "Split",
+				Description: "Passes a message per item of the collection, e.g. {{issue.subtasks}}.",
+			},
+			Config:     SplitConfig{},
+			Validator:  ValidateSplit,
+			OutputFunc: DescribeSplit,
+		},
+	)
+
+	return sat
+}
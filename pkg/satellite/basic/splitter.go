@@ -0,0 +1,146 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/expr"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/oklog/ulid"
+)
+
+type SplitConfig struct {
+	Path string `json:"path" required:"true" examples:"issue.subtasks" desc:"Path of the split collection."`
+}
+
+// Split returns a message per item of the collection at the path, in order:
+//
+//	{
+//	  "item": {...},
+//	  "parent": {...},
+//	  "split": {"index": 0, "total": 3, "batchId": "01BX5ZZKBKACTAV9WEVGEMMVRZ"}
+//	}
+//
+// Parent is the incoming message without the collection. The messages split from the same message
+// share the batch ID, so that a later step can put them together again.
+// An absent or empty collection results in no messages.
+func Split(ctx context.Context, config interface{}, m satellite.Message) ([]satellite.Message, error) {
+	path := config.(SplitConfig).Path
+	v, ok := m.Get(path)
+	if !ok || v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s is not a collection", path)
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	parent := satellite.Message(copyValue(map[string]interface{}(m)).(map[string]interface{}))
+	deletePath(parent, path)
+
+	batchID, err := newBatchID()
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]satellite.Message, len(items))
+	for i, item := range items {
+		messages[i] = satellite.Message{
+			"item":   item,
+			"parent": map[string]interface{}(parent),
+			"split": map[string]interface{}{
+				"index":   float64(i),
+				"total":   float64(len(items)),
+				"batchId": batchID,
+			},
+		}
+	}
+	return messages, nil
+}
+
+func newBatchID() (string, error) {
+	t := time.Now()
+	entropy := rand.New(rand.NewSource(t.UnixNano()))
+	id, err := ulid.New(ulid.Timestamp(t), entropy)
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// ValidateSplit checks that the path refers to a collection, if the incoming messages are known.
+func ValidateSplit(ctx context.Context, config interface{}) error {
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+	_, err := describeSplit(config.(SplitConfig).Path, input)
+	return err
+}
+
+// DescribeSplit describes the messages returned by Split.
+// They are unknown if the incoming messages or the items of the collection are not described.
+func DescribeSplit(ctx context.Context, config interface{}, input schema.Fields) (schema.Fields, error) {
+	return describeSplit(config.(SplitConfig).Path, input)
+}
+
+func describeSplit(path string, input schema.Fields) (schema.Fields, error) {
+	if !validPath(path) {
+		return nil, schema.ValidationErrors{{Path: "path", Message: "must be a dot-separated path of object keys"}}
+	}
+	if input == nil {
+		return nil, nil
+	}
+
+	f, ok := expr.Lookup(input, path)
+	if !ok {
+		return nil, schema.ValidationErrors{{Path: "path", Message: "unknown field " + path}}
+	}
+	if f == nil {
+		return nil, nil
+	}
+	if f.Type != schema.Collection {
+		return nil, schema.ValidationErrors{{Path: "path", Message: "must be a collection, not " + f.Type.Name}}
+	}
+	item := copyField(f.Elem())
+	if item == nil {
+		return nil, nil
+	}
+	item.Name, item.Required = "Item", true
+
+	parent := copyFields(input)
+	removeField(parent, path)
+
+	return schema.Fields{
+		"item":   item,
+		"parent": {Name: "Parent", Type: schema.Object, Required: true, Fields: parent},
+		"split": {
+			Name:     "Split",
+			Type:     schema.Object,
+			Required: true,
+			Fields: schema.Fields{
+				"index": {
+					Name:        "Index",
+					Type:        schema.Integer,
+					Description: "Position of the item in the collection, from zero",
+					Required:    true,
+				},
+				"total": {
+					Name:        "Total",
+					Type:        schema.Integer,
+					Description: "Number of the items in the collection",
+					Required:    true,
+				},
+				"batchId": {
+					Name:        "Batch ID",
+					Type:        schema.String,
+					Description: "ID shared by the messages split from the same message",
+					Required:    true,
+				},
+			},
+		},
+	}, nil
+}
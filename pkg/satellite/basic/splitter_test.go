@@ -0,0 +1,161 @@
+package basic
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+func issueMessage() satellite.Message {
+	return satellite.Message{
+		"project": "GO",
+		"issue": map[string]interface{}{
+			"key": "GO-1",
+			"subtasks": []interface{}{
+				map[string]interface{}{"key": "GO-2"},
+				map[string]interface{}{"key": "GO-3"},
+			},
+		},
+	}
+}
+
+func TestSplit(t *testing.T) {
+	m := issueMessage()
+	messages, err := Split(context.Background(), SplitConfig{Path: "issue.subtasks"}, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+
+	parent := map[string]interface{}{"project": "GO", "issue": map[string]interface{}{"key": "GO-1"}}
+	batchID := messages[0]["split"].(map[string]interface{})["batchId"]
+	if id, ok := batchID.(string); !ok || id == "" {
+		t.Errorf("got the batch ID %v", batchID)
+	}
+	for i, got := range messages {
+		want := satellite.Message{
+			"item":   map[string]interface{}{"key": []string{"GO-2", "GO-3"}[i]},
+			"parent": parent,
+			"split":  map[string]interface{}{"index": float64(i), "total": 2.0, "batchId": batchID},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("message %d: got %v, want %v", i, got, want)
+		}
+	}
+
+	if !reflect.DeepEqual(m, issueMessage()) {
+		t.Errorf("the incoming message is changed: %v", m)
+	}
+}
+
+func TestSplitNothing(t *testing.T) {
+	tests := []struct {
+		name string
+		m    satellite.Message
+	}{
+		{"absent", satellite.Message{"issue": map[string]interface{}{}}},
+		{"absent parent", satellite.Message{}},
+		{"null", satellite.Message{"issue": map[string]interface{}{"subtasks": nil}}},
+		{"empty", satellite.Message{"issue": map[string]interface{}{"subtasks": []interface{}{}}}},
+	}
+	for _, tt := range tests {
+		messages, err := Split(context.Background(), SplitConfig{Path: "issue.subtasks"}, tt.m)
+		if messages != nil || err != nil {
+			t.Errorf("%s: got %v, %v", tt.name, messages, err)
+		}
+	}
+}
+
+func TestSplitNotCollection(t *testing.T) {
+	for _, v := range []interface{}{"GO-2", 2.0, true, map[string]interface{}{"key": "GO-2"}} {
+		m := satellite.Message{"issue": map[string]interface{}{"subtasks": v}}
+		messages, err := Split(context.Background(), SplitConfig{Path: "issue.subtasks"}, m)
+		if err == nil {
+			t.Errorf("%v: got %v", v, messages)
+		}
+	}
+}
+
+func TestDescribeSplit(t *testing.T) {
+	subtask := &schema.Field{Name: "Subtask", Type: schema.Object, Fields: schema.Fields{
+		"key": {Name: "Key", Type: schema.String, Required: true},
+	}}
+	input := schema.Fields{
+		"project": {Name: "Project", Type: schema.String},
+		"issue": {Name: "Issue", Type: schema.Object, Required: true, Fields: schema.Fields{
+			"key":      {Name: "Key", Type: schema.String, Required: true},
+			"subtasks": {Name: "Subtasks", Type: schema.Collection, Items: subtask},
+			"labels":   {Name: "Labels", Type: schema.Collection},
+			"data":     {Name: "Data", Type: schema.Object},
+		}},
+	}
+
+	f, err := DescribeSplit(context.Background(), SplitConfig{Path: "issue.subtasks"}, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	item := &schema.Field{Name: "Item", Type: schema.Object, Required: true, Fields: subtask.Fields}
+	if !reflect.DeepEqual(f["item"], item) {
+		t.Errorf("got the item %+v", f["item"])
+	}
+	parent := f["parent"].Fields
+	if _, ok := parent["issue"].Fields["subtasks"]; ok {
+		t.Error("the parent has the split collection")
+	}
+	if _, ok := parent["issue"].Fields["key"]; !ok || parent["project"] == nil {
+		t.Errorf("got the parent %v", parent)
+	}
+	if _, ok := input["issue"].Fields["subtasks"]; !ok {
+		t.Error("the input is changed")
+	}
+	if subtask.Name != "Subtask" || subtask.Required {
+		t.Error("the items of the input are changed")
+	}
+	split := f["split"].Fields
+	if len(split) != 3 || split["index"].Type.Name != schema.Integer.Name || split["batchId"] == nil {
+		t.Errorf("got the split %v", split)
+	}
+
+	tests := []struct {
+		path string
+		err  bool
+	}{
+		{"issue.labels", false},
+		{"issue.data.items", false},
+		{"issue.key", true},
+		{"issue.unknown", true},
+		{"issue..subtasks", true},
+		{"issue.subtasks[]", true},
+	}
+	for _, tt := range tests {
+		f, err := DescribeSplit(context.Background(), SplitConfig{Path: tt.path}, input)
+		if f != nil || (err != nil) != tt.err {
+			t.Errorf("%s: got %v, %v", tt.path, f, err)
+		}
+
+		ctx := context.WithValue(context.Background(), satellite.ContextKeyInput, input)
+		if err := ValidateSplit(ctx, SplitConfig{Path: tt.path}); (err != nil) != tt.err {
+			t.Errorf("%s: got %v validating", tt.path, err)
+		}
+	}
+
+	// Unknown incoming messages.
+	f, err = DescribeSplit(context.Background(), SplitConfig{Path: "issue.subtasks"}, nil)
+	if f != nil || err != nil {
+		t.Errorf("got %v, %v for unknown input", f, err)
+	}
+}
+
+func TestSplitDispatch(t *testing.T) {
+	res, err := New(nil).Dispatch(
+		context.Background(), satellite.KindSplitter, "Split", []byte(`{"path":"issue.subtasks"}`), issueMessage(),
+	)
+	if err != nil || len(res) != 2 {
+		t.Errorf("got %v, %v", res, err)
+	}
+}
@@ -0,0 +1,270 @@
+package cron
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+type TickConfig struct {
+	Schedule string `json:"schedule" required:"true" examples:"0 */5 * * * *,@daily" desc:"Cron expression."`
+	TimeZone string `json:"timeZone" examples:"UTC,Europe/Berlin" desc:"IANA time zone of the schedule, defaults to UTC."`
+}
+
+// Ticker fires the ticks of the schedules.
+//
+// A trigger is started on the one satellite instance that has received its StartTriggerRequest,
+// that instance registers the trigger and every instance runs the registered triggers, see Sync.
+// An instance fires a tick only if it acquires the lease of the tick, so each tick is fired once
+// as long as any of the instances is running. The trigger is unregistered when it is stopped,
+// but not when its instance shuts down, so that the other instances go on firing the ticks.
+type Ticker struct {
+	leaser       transport.Leaser
+	registry     registry
+	emitter      func(topic string) satellite.Emitter
+	leaseTTL     time.Duration
+	syncInterval time.Duration
+	logger       log.Logger
+
+	mu sync.Mutex
+	// owned are the triggers started on this instance.
+	owned map[string]bool
+	// replicas are the triggers started on the other instances and run by this one.
+	replicas map[string]context.CancelFunc
+	running  sync.WaitGroup
+	// stopping is the context of Sync, it is done when the satellite stops.
+	stopping context.Context
+}
+
+// Tick registers the trigger and emits a message at every time matching the schedule until
+// the trigger is stopped. If the trigger falls behind, e.g. the message broker is slow,
+// the missed ticks are skipped. A tick that cannot be leased or emitted is logged and skipped,
+// the trigger goes on with the next one.
+func (t *Ticker) Tick(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	c := config.(TickConfig)
+	s, err := c.schedule()
+	if err != nil {
+		return err
+	}
+
+	id, _ := ctx.Value(satellite.ContextKeyTriggerID).(string)
+	ns, _ := ctx.Value(satellite.ContextKeyNamespace).(string)
+	topic, _ := ctx.Value(satellite.ContextKeyTopic).(string)
+
+	err = t.registry.register(ns, id, entry{Config: c, Topic: topic})
+	if err != nil {
+		return err
+	}
+	t.own(id)
+	defer t.disown(id)
+
+	t.tick(ctx, ns, id, s, e)
+
+	if !t.shuttingDown() {
+		err = t.registry.unregister(ns, id)
+		if err != nil {
+			level.Error(t.logger).Log("err", err, "context", "unregister", "id", id)
+		}
+	}
+	return nil
+}
+
+// tick fires the ticks of the trigger until the context is canceled.
+func (t *Ticker) tick(ctx context.Context, ns, id string, s *Schedule, e satellite.Emitter) {
+	prefix := transport.NamespacedTopic(ns, "cron.lease."+id+".")
+
+	next := s.Next(time.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		fired := time.Now()
+		// The trigger may have been stopped on another instance since the last sync.
+		ok, err := t.registry.registered(ns, id)
+		if err != nil {
+			level.Error(t.logger).Log("err", err, "context", "registered", "id", id, "scheduledAt", next)
+		}
+		if ok {
+			ok, err = t.leaser.Lease(prefix+strconv.FormatInt(next.Unix(), 10), t.leaseTTL)
+			if err != nil {
+				level.Error(t.logger).Log("err", err, "context", "Lease", "id", id, "scheduledAt", next)
+			}
+		}
+		if ok {
+			err = e.Emit(ctx, satellite.Message{
+				"scheduledAt": next,
+				"firedAt":     fired.In(next.Location()),
+			})
+			if err != nil {
+				level.Error(t.logger).Log("err", err, "context", "Emit", "id", id, "scheduledAt", next)
+			}
+		}
+
+		scheduled := next
+		next = s.Next(scheduled)
+		if now := time.Now(); next.Before(now) {
+			next = s.Next(now)
+		}
+	}
+}
+
+// Sync runs the triggers registered by the other instances until the context is canceled,
+// it checks the registry every syncInterval.
+func (t *Ticker) Sync(ctx context.Context) {
+	ns, _ := ctx.Value(satellite.ContextKeyNamespace).(string)
+
+	t.mu.Lock()
+	t.stopping = ctx
+	t.mu.Unlock()
+
+	timer := time.NewTicker(t.syncInterval)
+	defer timer.Stop()
+	for {
+		err := t.sync(ctx, ns)
+		if err != nil {
+			level.Error(t.logger).Log("err", err, "context", "Sync")
+		}
+
+		select {
+		case <-ctx.Done():
+			t.running.Wait()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// sync starts the replicas of the registered triggers, which are not running on this instance,
+// and stops the replicas of the unregistered ones.
+func (t *Ticker) sync(ctx context.Context, ns string) error {
+	entries, err := t.registry.entries(ns)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, cancel := range t.replicas {
+		if _, ok := entries[id]; !ok || t.owned[id] {
+			cancel()
+			delete(t.replicas, id)
+		}
+	}
+
+	for id, e := range entries {
+		if t.owned[id] || t.replicas[id] != nil {
+			continue
+		}
+		s, err := e.Config.schedule()
+		if err != nil {
+			level.Error(t.logger).Log("err", err, "context", "sync", "id", id)
+			continue
+		}
+
+		rctx, cancel := context.WithCancel(ctx)
+		t.replicas[id] = cancel
+		t.running.Add(1)
+		go func(id string, s *Schedule, em satellite.Emitter) {
+			defer t.running.Done()
+			t.tick(rctx, ns, id, s, em)
+		}(id, s, t.emitter(e.Topic))
+	}
+	return nil
+}
+
+// own marks the trigger as started on this instance and stops its replica, if any.
+func (t *Ticker) own(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.owned[id] = true
+	if cancel, ok := t.replicas[id]; ok {
+		cancel()
+		delete(t.replicas, id)
+	}
+}
+
+func (t *Ticker) disown(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.owned, id)
+}
+
+// shuttingDown reports whether the satellite stops, rather than the trigger.
+// The satellite's context is canceled before the contexts of its triggers.
+func (t *Ticker) shuttingDown() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopping != nil && t.stopping.Err() != nil
+}
+
+func (c TickConfig) schedule() (*Schedule, error) {
+	loc := time.UTC
+	if c.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Parse(c.Schedule, loc)
+}
+
+// ValidateTick checks the schedule and the time zone.
+func ValidateTick(ctx context.Context, config interface{}) error {
+	c := config.(TickConfig)
+
+	var errs schema.ValidationErrors
+	loc := time.UTC
+	if c.TimeZone != "" {
+		var err error
+		loc, err = time.LoadLocation(c.TimeZone)
+		if err != nil {
+			loc = time.UTC
+			errs = append(errs, schema.ValidationError{Path: "timeZone", Message: "is not a known time zone"})
+		}
+	}
+	s, err := Parse(c.Schedule, loc)
+	switch {
+	case err != nil:
+		errs = append(errs, schema.ValidationError{Path: "schedule", Message: err.Error()})
+	case s.Next(time.Now()).IsZero():
+		errs = append(errs, schema.ValidationError{Path: "schedule", Message: "never matches"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// TickFields describes the messages emitted by the trigger.
+func TickFields() schema.Fields {
+	return schema.Fields{
+		"scheduledAt": &schema.Field{
+			Name:        "Scheduled at",
+			Type:        schema.Datetime,
+			Description: "Time of the tick according to the schedule, in its time zone",
+			Required:    true,
+		},
+		"firedAt": &schema.Field{
+			Name:        "Fired at",
+			Type:        schema.Datetime,
+			Description: "Time the tick has actually been fired at, in the time zone of the schedule",
+			Required:    true,
+		},
+	}
+}
@@ -0,0 +1,176 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/go-kit/kit/log"
+)
+
+// memory is a registry and a leaser shared by the instances of a test.
+type memory struct {
+	mu       sync.Mutex
+	triggers map[string]entry
+	leases   map[string]bool
+}
+
+func newMemory() *memory {
+	return &memory{triggers: make(map[string]entry), leases: make(map[string]bool)}
+}
+
+func (m *memory) register(ns, id string, e entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.triggers[ns+id] = e
+	return nil
+}
+
+func (m *memory) unregister(ns, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.triggers, ns+id)
+	return nil
+}
+
+func (m *memory) registered(ns, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.triggers[ns+id]
+	return ok, nil
+}
+
+func (m *memory) entries(ns string) (map[string]entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	res := make(map[string]entry)
+	for id, e := range m.triggers {
+		res[id[len(ns):]] = e
+	}
+	return res, nil
+}
+
+func (m *memory) Lease(name string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.leases[name] {
+		return false, nil
+	}
+	m.leases[name] = true
+	return true, nil
+}
+
+// emits counts the messages emitted by the instances per topic.
+type emits struct {
+	mu sync.Mutex
+	n  map[string]int
+}
+
+func (e *emits) emitter(topic string) satellite.Emitter {
+	return satellite.EmitterFunc(func(ctx context.Context, m satellite.Message) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		e.n[topic]++
+		return nil
+	})
+}
+
+func (e *emits) count(topic string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.n[topic]
+}
+
+func newTicker(m *memory, e *emits) *Ticker {
+	return &Ticker{
+		leaser:       m,
+		registry:     m,
+		emitter:      e.emitter,
+		leaseTTL:     time.Minute,
+		syncInterval: 10 * time.Millisecond,
+		logger:       log.NewNopLogger(),
+		owned:        make(map[string]bool),
+		replicas:     make(map[string]context.CancelFunc),
+	}
+}
+
+func triggerContext(parent context.Context, id string) context.Context {
+	ctx := context.WithValue(parent, satellite.ContextKeyTriggerID, id)
+	return context.WithValue(ctx, satellite.ContextKeyTopic, "topic."+id)
+}
+
+func TestTickerReplicas(t *testing.T) {
+	m, e := newMemory(), &emits{n: make(map[string]int)}
+	owner, other := newTicker(m, e), newTicker(m, e)
+
+	ownerCtx, stopOwner := context.WithCancel(context.Background())
+	otherCtx, stopOther := context.WithCancel(context.Background())
+	defer stopOther()
+	go owner.Sync(ownerCtx)
+	go other.Sync(otherCtx)
+
+	ctx, stop := context.WithCancel(ownerCtx)
+	defer stop()
+	done := make(chan error)
+	go func() {
+		done <- owner.Tick(triggerContext(ctx, "a"), TickConfig{Schedule: "* * * * * *"}, e.emitter("topic.a"))
+	}()
+
+	time.Sleep(2500 * time.Millisecond)
+	n := e.count("topic.a")
+	if n < 2 || n > 3 {
+		t.Errorf("got %d ticks in 2.5s, want one per second", n)
+	}
+	other.mu.Lock()
+	replicated := other.replicas["a"] != nil
+	other.mu.Unlock()
+	if !replicated {
+		t.Error("the trigger does not run on the other instance")
+	}
+
+	// The owner shuts down, the other instance goes on firing the ticks.
+	stopOwner()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.registered("", "a"); !ok {
+		t.Fatal("the trigger has been unregistered on shutdown")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if e.count("topic.a") <= n {
+		t.Error("no ticks after the owner has shut down")
+	}
+}
+
+func TestTickerStop(t *testing.T) {
+	m, e := newMemory(), &emits{n: make(map[string]int)}
+	owner, other := newTicker(m, e), newTicker(m, e)
+
+	bg, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	go owner.Sync(bg)
+	go other.Sync(bg)
+
+	ctx, stop := context.WithCancel(bg)
+	done := make(chan error)
+	go func() {
+		done <- owner.Tick(triggerContext(ctx, "a"), TickConfig{Schedule: "* * * * * *"}, e.emitter("topic.a"))
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := m.registered("", "a"); ok {
+		t.Error("the stopped trigger is still registered")
+	}
+	time.Sleep(100 * time.Millisecond)
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	if len(other.replicas) != 0 {
+		t.Errorf("got replicas %v", other.replicas)
+	}
+}
@@ -0,0 +1,76 @@
+package cron
+
+import (
+	"encoding/json"
+
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/garyburd/redigo/redis"
+)
+
+// registry keeps the started triggers, so that every instance of the satellite runs them.
+// The triggers are kept per namespace, see satellite.TransportConfig.Namespace.
+type registry interface {
+	register(ns, id string, e entry) error
+	unregister(ns, id string) error
+	registered(ns, id string) (bool, error)
+	entries(ns string) (map[string]entry, error)
+}
+
+// entry is a trigger started on one of the instances.
+type entry struct {
+	Config TickConfig `json:"config"`
+	Topic  string     `json:"topic"`
+}
+
+// redisRegistry keeps the triggers in a redis hash keyed by the trigger IDs.
+type redisRegistry struct {
+	pool *redis.Pool
+}
+
+func (r redisRegistry) key(ns string) string {
+	return transport.NamespacedTopic(ns, "cron.triggers")
+}
+
+func (r redisRegistry) register(ns, id string, e entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	_, err = con.Do("HSET", r.key(ns), id, data)
+	return err
+}
+
+func (r redisRegistry) unregister(ns, id string) error {
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	_, err := con.Do("HDEL", r.key(ns), id)
+	return err
+}
+
+func (r redisRegistry) registered(ns, id string) (bool, error) {
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	return redis.Bool(con.Do("HEXISTS", r.key(ns), id))
+}
+
+// entries returns the registered triggers. The entries that cannot be decoded are skipped.
+func (r redisRegistry) entries(ns string) (map[string]entry, error) {
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	m, err := redis.StringMap(con.Do("HGETALL", r.key(ns)))
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]entry, len(m))
+	for id, data := range m {
+		var e entry
+		if json.Unmarshal([]byte(data), &e) == nil {
+			res[id] = e
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,70 @@
+package cron
+
+import (
+	"context"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/garyburd/redigo/redis"
+	"github.com/go-kit/kit/log"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "Cron",
+	Version:     "0.1.0-alpha",
+	Description: "Triggers missions on a schedule.",
+}
+
+// Config configures the satellite, e.g. with GOGARIN_SATELLITE_CRON_LEASETTLINMS=60000.
+type Config struct {
+	// LeaseTTLInMs is how long the lease of a tick is held. It must exceed the difference
+	// between the clocks of the satellite instances, so that a tick is not fired twice
+	// by the instances running the same trigger, see Ticker.
+	// The default LeaseTTLInMs is 60000ms/60s.
+	LeaseTTLInMs int `default:"60000"`
+
+	// SyncIntervalInMs specifies how often an instance checks the triggers started
+	// on the other instances, so that it runs the started ones and stops the stopped ones.
+	// The default SyncIntervalInMs is 5000ms/5s.
+	SyncIntervalInMs int `default:"5000"`
+}
+
+// New returns the satellite with all its abilities. The started triggers are kept
+// in the redis database of the pool, every instance of the satellite must use the same one.
+// New panics if the connection does not implement transport.Leaser.
+func New(conn transport.Connection, pool *redis.Pool, c Config, options ...satellite.Option) *satellite.Satellite {
+	leaser, ok := conn.(transport.Leaser)
+	if !ok {
+		panic("cron: the connection does not implement transport.Leaser")
+	}
+
+	sat := satellite.New(conn, Info, options...)
+	t := &Ticker{
+		leaser:       leaser,
+		registry:     redisRegistry{pool: pool},
+		emitter:      sat.Emitter,
+		leaseTTL:     time.Duration(c.LeaseTTLInMs) * time.Millisecond,
+		syncInterval: time.Duration(c.SyncIntervalInMs) * time.Millisecond,
+		logger:       log.With(sat.Logger(), "trigger", "Tick"),
+		owned:        make(map[string]bool),
+		replicas:     make(map[string]context.CancelFunc),
+	}
+	sat.Go(t.Sync)
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: t.Tick,
+			Info: satellite.AbilityInfo{
+				Name:        "Tick",
+				Description: "Triggers at the times matching a cron expression.",
+			},
+			Config:    TickConfig{},
+			Output:    TickFields(),
+			Validator: ValidateTick,
+		},
+	)
+
+	return sat
+}
@@ -0,0 +1,268 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	second, minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar are true if the day of month or week is a wildcard.
+	// Unless one of them is, a day matches either of them, as in the standard cron.
+	domStar, dowStar bool
+
+	loc *time.Location
+}
+
+// bounds of the values of a field.
+type bounds struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	seconds = bounds{name: "second", min: 0, max: 59}
+	minutes = bounds{name: "minute", min: 0, max: 59}
+	hours   = bounds{name: "hour", min: 0, max: 23}
+	doms    = bounds{name: "day of month", min: 1, max: 31}
+	months  = bounds{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is both 0 and 7.
+	dows = bounds{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors are the shorthands of the common expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse parses a cron expression in the time zone, e.g. "*/15 * * * * *" for every 15 seconds.
+// A nil time zone is UTC.
+// The expression has six fields: second, minute, hour, day of month, month and day of week.
+// The second may be omitted, then it is 0. A field is a comma-separated list of
+// values, ranges (1-5) and steps (*/2, 1-9/2), ? is a wildcard too.
+// Months and days of week may be given by their names, e.g. jan or mon.
+// The expression may also be one of @yearly, @monthly, @weekly, @daily and @hourly.
+func Parse(expr string, loc *time.Location) (*Schedule, error) {
+	if d, ok := descriptors[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = d
+	}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %d in %q", len(fields), expr)
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	s := &Schedule{loc: loc}
+	var err error
+	parsers := []struct {
+		bits *uint64
+		b    bounds
+	}{
+		{&s.second, seconds}, {&s.minute, minutes}, {&s.hour, hours},
+		{&s.dom, doms}, {&s.month, months}, {&s.dow, dows},
+	}
+	for i, p := range parsers {
+		*p.bits, err = parseField(fields[i], p.b)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = isWildcard(fields[3])
+	s.dowStar = isWildcard(fields[5])
+	return s, nil
+}
+
+func isWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseField returns the bits of the values listed by the field.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rng = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s %q", b.name, part)
+			}
+		}
+
+		var from, to int
+		switch {
+		case isWildcard(rng):
+			from, to = b.min, b.max
+		case strings.Contains(rng, "-"):
+			i := strings.Index(rng, "-")
+			var err error
+			from, err = b.value(rng[:i])
+			if err != nil {
+				return 0, err
+			}
+			to, err = b.value(rng[i+1:])
+			if err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			from, err = b.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			to = from
+			if step > 1 {
+				// 5/10 means 5-max/10.
+				to = b.max
+			}
+		}
+		if from > to {
+			return 0, fmt.Errorf("invalid range in %s %q", b.name, part)
+		}
+
+		for v := from; v <= to; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// value parses a number or a name within the bounds.
+func (b bounds) value(s string) (int, error) {
+	v, ok := b.names[strings.ToLower(s)]
+	if !ok {
+		var err error
+		v, err = strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", b.name, s)
+		}
+	}
+	if v < b.min || v > b.max {
+		return 0, fmt.Errorf("%s %d is out of range %d-%d", b.name, v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// maxYears limits the search of the next time, e.g. for Feb 30 which never comes.
+const maxYears = 5
+
+// Next returns the first time after t that matches the schedule, in the schedule's time zone.
+// It returns the zero time if nothing matches within 5 years.
+// The times skipped by a daylight saving time transition do not match,
+// the repeated ones match on both occasions.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := s.loc
+	// Start from the next whole second.
+	t = t.In(loc).Add(time.Second - time.Duration(t.Nanosecond()))
+
+	// reset is true once the smaller units have been set to their minimum.
+	reset := false
+	limit := t.Year() + maxYears
+
+search:
+	for t.Year() <= limit {
+		for !has(s.month, int(t.Month())) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+			}
+			t = t.AddDate(0, 1, 0)
+			if t.Month() == time.January {
+				continue search
+			}
+		}
+
+		for !s.dayMatches(t) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+			}
+			t = t.AddDate(0, 0, 1)
+			// Midnight may be skipped by a daylight saving time transition.
+			if t.Hour() != 0 {
+				if t.Hour() > 12 {
+					t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+				} else {
+					t = t.Add(-time.Duration(t.Hour()) * time.Hour)
+				}
+			}
+			if t.Day() == 1 {
+				continue search
+			}
+		}
+
+		for !has(s.hour, t.Hour()) {
+			if !reset {
+				reset = true
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+			}
+			t = t.Add(time.Hour)
+			if t.Hour() == 0 {
+				continue search
+			}
+		}
+
+		for !has(s.minute, t.Minute()) {
+			if !reset {
+				reset = true
+				t = t.Truncate(time.Minute)
+			}
+			t = t.Add(time.Minute)
+			if t.Minute() == 0 {
+				continue search
+			}
+		}
+
+		for !has(s.second, t.Second()) {
+			if !reset {
+				reset = true
+				t = t.Truncate(time.Second)
+			}
+			t = t.Add(time.Second)
+			if t.Second() == 0 {
+				continue search
+			}
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<uint(v)) != 0
+}
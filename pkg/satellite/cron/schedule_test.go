@@ -0,0 +1,117 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	utc := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		expr string
+		loc  *time.Location
+		from string
+		want []string
+	}{
+		// Seconds and steps.
+		{expr: "*/15 * * * * *", from: "2020-01-01T10:00:07Z", want: []string{
+			"2020-01-01T10:00:15Z", "2020-01-01T10:00:30Z", "2020-01-01T10:00:45Z", "2020-01-01T10:01:00Z",
+		}},
+		{expr: "30 * * * * *", from: "2020-01-01T10:00:30Z", want: []string{"2020-01-01T10:01:30Z"}},
+		{expr: "*/5 * * * *", from: "2020-01-01T10:03:00Z", want: []string{
+			"2020-01-01T10:05:00Z", "2020-01-01T10:10:00Z",
+		}},
+		{expr: "0 0 9-17/4 * * *", from: "2020-01-01T10:00:00Z", want: []string{
+			"2020-01-01T13:00:00Z", "2020-01-01T17:00:00Z", "2020-01-02T09:00:00Z",
+		}},
+		{expr: "0 0 0 5/10 * *", from: "2020-01-01T00:00:00Z", want: []string{
+			"2020-01-05T00:00:00Z", "2020-01-15T00:00:00Z", "2020-01-25T00:00:00Z", "2020-02-05T00:00:00Z",
+		}},
+		{expr: "@hourly", from: "2020-12-31T23:30:00Z", want: []string{"2021-01-01T00:00:00Z"}},
+		// Sunday is both 0 and 7.
+		{expr: "0 0 0 * * 7", from: "2020-01-01T00:00:00Z", want: []string{"2020-01-05T00:00:00Z"}},
+		{expr: "0 0 0 * * 0", from: "2020-01-01T00:00:00Z", want: []string{"2020-01-05T00:00:00Z"}},
+		{expr: "0 0 0 * * 5-7", from: "2020-01-01T00:00:00Z", want: []string{
+			"2020-01-03T00:00:00Z", "2020-01-04T00:00:00Z", "2020-01-05T00:00:00Z", "2020-01-10T00:00:00Z",
+		}},
+		// A day matches either the day of month or the day of week, unless one of them is a wildcard.
+		{expr: "0 0 0 13 * fri", from: "2020-01-01T00:00:00Z", want: []string{
+			"2020-01-03T00:00:00Z", "2020-01-10T00:00:00Z", "2020-01-13T00:00:00Z", "2020-01-17T00:00:00Z",
+		}},
+		{expr: "0 0 0 13 * *", from: "2020-01-01T00:00:00Z", want: []string{
+			"2020-01-13T00:00:00Z", "2020-02-13T00:00:00Z",
+		}},
+		{expr: "0 0 0 * * fri", from: "2020-01-01T00:00:00Z", want: []string{"2020-01-03T00:00:00Z"}},
+		{expr: "0 0 0 29 2 *", from: "2020-03-01T00:00:00Z", want: []string{"2024-02-29T00:00:00Z"}},
+		// The skipped times do not match, the repeated ones match twice.
+		{expr: "0 30 2 * * *", loc: berlin, from: "2020-03-28T03:00:00+01:00", want: []string{
+			"2020-03-30T02:30:00+02:00",
+		}},
+		{expr: "0 30 2 * * *", loc: berlin, from: "2020-10-25T00:00:00+02:00", want: []string{
+			"2020-10-25T02:30:00+02:00", "2020-10-25T02:30:00+01:00", "2020-10-26T02:30:00+01:00",
+		}},
+		{expr: "@daily", loc: berlin, from: "2020-03-28T12:00:00+01:00", want: []string{
+			"2020-03-29T00:00:00+01:00", "2020-03-30T00:00:00+02:00",
+		}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.expr, tt.loc)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		next := utc(tt.from)
+		for _, w := range tt.want {
+			next = s.Next(next)
+			if !next.Equal(utc(w)) {
+				t.Errorf("%s: got %v, want %v", tt.expr, next, w)
+				break
+			}
+			if tt.loc != nil && next.Location() != tt.loc {
+				t.Errorf("%s: got %v, want the schedule's time zone", tt.expr, next)
+			}
+		}
+	}
+}
+
+func TestScheduleNever(t *testing.T) {
+	s, err := Parse("0 0 0 30 2 *", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("got %v", next)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"* * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"*/x * * * * *",
+		"5-1 * * * * *",
+		"0 0 0 * foo *",
+		"@every",
+	} {
+		if _, err := Parse(expr, nil); err == nil {
+			t.Errorf("%s: no error", expr)
+		}
+	}
+}
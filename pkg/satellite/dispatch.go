@@ -0,0 +1,87 @@
+package satellite
+
+import (
+	"context"
+	"errors"
+)
+
+// Kind is a kind of ability.
+type Kind string
+
+// Kinds of abilities.
+const (
+	KindTrigger  Kind = "trigger"
+	KindFilter   Kind = "filter"
+	KindModifier Kind = "modifier"
+	KindAction   Kind = "action"
+	KindSplitter Kind = "splitter"
+)
+
+// ErrUnknownAbility is returned when the satellite has no ability of the requested kind and name.
+var ErrUnknownAbility = errors.New("unknown ability")
+
+// Dispatch executes the named ability of the kind on the incoming message with the JSON encoded config.
+// The config is validated and decoded like in ValidateConfig, including the ability's Validator,
+// and the message is validated against the ability's Input. It returns the messages to pass
+// to the next step of a mission:
+//   - a filter returns the message if it passes and nothing otherwise;
+//   - a modifier returns the modified message;
+//   - an action returns its result, if any;
+//   - a splitter returns the split messages.
+//
+// Triggers are not dispatched, they run on their own and emit messages.
+func (s *Satellite) Dispatch(ctx context.Context, kind Kind, name string, config []byte, m Message) ([]Message, error) {
+	a, ok := s.abilityConfig(kind, name)
+	if !ok || kind == KindTrigger {
+		return nil, ErrUnknownAbility
+	}
+	c, err := a.decodeWith(ctx, config, m)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case KindFilter:
+		for _, f := range s.Filters {
+			if f.Info.Name != name {
+				continue
+			}
+			pass, err := f.Call(ctx, c, m)
+			if err != nil || !pass {
+				return nil, err
+			}
+			return []Message{m}, nil
+		}
+	case KindModifier:
+		for _, mod := range s.Modifiers {
+			if mod.Info.Name != name {
+				continue
+			}
+			res, err := mod.Call(ctx, c, m)
+			if err != nil {
+				return nil, err
+			}
+			return []Message{res}, nil
+		}
+	case KindAction:
+		for _, action := range s.Actions {
+			if action.Info.Name != name {
+				continue
+			}
+			res, err := action.Call(ctx, c, m)
+			if err != nil || res == nil {
+				return nil, err
+			}
+			return []Message{res}, nil
+		}
+	case KindSplitter:
+		for _, sp := range s.Splitters {
+			if sp.Info.Name != name {
+				continue
+			}
+			return sp.Call(ctx, c, m)
+		}
+	}
+
+	return nil, ErrUnknownAbility
+}
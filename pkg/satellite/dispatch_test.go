@@ -0,0 +1,55 @@
+package satellite
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+type limitConfig struct {
+	Limit int64 `json:"limit"`
+}
+
+func TestDispatchValidates(t *testing.T) {
+	s := New(nil, Info{Name: "test"})
+	called := false
+	s.AddFilter(Filter{
+		Info:   AbilityInfo{Name: "limit"},
+		Config: limitConfig{},
+		Input:  schema.Fields{"count": {Name: "Count", Type: schema.Integer, Required: true}},
+		Validator: func(ctx context.Context, config interface{}) error {
+			if config.(limitConfig).Limit < 0 {
+				return schema.ValidationErrors{{Path: "limit", Message: "must not be negative"}}
+			}
+			return nil
+		},
+		Call: func(ctx context.Context, config interface{}, m Message) (bool, error) {
+			called = true
+			return true, nil
+		},
+	})
+
+	_, err := s.Dispatch(context.Background(), KindFilter, "limit", []byte(`{"limit":-1}`), Message{})
+	want := schema.ValidationErrors{
+		{Path: "message.count", Message: "is required"},
+		{Path: "config.limit", Message: "must not be negative"},
+	}
+	if !reflect.DeepEqual(err, want) {
+		t.Errorf("got %v, want %v", err, want)
+	}
+	if called {
+		t.Error("the filter has been called with an invalid config")
+	}
+
+	res, err := s.Dispatch(context.Background(), KindFilter, "limit", []byte(`{"limit":1}`), Message{"count": 1.0})
+	if err != nil || len(res) != 1 || !called {
+		t.Errorf("got %v, %v", res, err)
+	}
+
+	_, err = s.Dispatch(context.Background(), KindTrigger, "limit", nil, Message{})
+	if err != ErrUnknownAbility {
+		t.Errorf("got %v", err)
+	}
+}
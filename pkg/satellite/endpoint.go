@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
 	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/antonkuzmenko/gogarin/pkg/transport/redis"
 	"github.com/go-kit/kit/endpoint"
 )
 
@@ -17,11 +19,19 @@ func makeRegisterEndpoint(config Config, conn transport.Connection) endpoint.End
 		time.Duration(config.Transport.RegisterTimeoutInSec)*time.Second,
 		encodeJSONRequest,
 		decodeJSONRegisterResponse,
+		transport.ClientNamespace(config.Transport.Namespace),
 	).Endpoint()
 }
 
+// decodeJSONRegisterResponse decodes the Info confirming the registration.
+// An error response of the space center is returned as the error.
 func decodeJSONRegisterResponse(_ context.Context, data interface{}) (response interface{}, err error) {
 	r := data.([]byte)
+	err = redis.DecodeError(r)
+	if err != nil {
+		return nil, err
+	}
+
 	var i Info
 	err = json.Unmarshal(r, &i)
 	if err != nil {
@@ -39,3 +49,163 @@ func encodeJSONRequest(_ context.Context, data interface{}) (request interface{}
 	}
 	return buf.Bytes(), nil
 }
+
+// DispatchRequest is a request to execute an ability on a message, see Satellite.Dispatch.
+type DispatchRequest struct {
+	Config  json.RawMessage
+	Message Message
+}
+
+// DispatchResponse contains the messages to pass to the next step of a mission.
+// Errors lists the fields of the config or the message that are invalid.
+type DispatchResponse struct {
+	Messages []Message
+	Error    string                  `json:",omitempty"`
+	Errors   schema.ValidationErrors `json:",omitempty"`
+}
+
+// ValidateConfigRequest is a request to validate the config of an ability, see Satellite.ValidateConfig.
+// Input describes the messages the ability receives in the mission, if they are known.
+// It is encoded as JSON Schema, see AbilityManifest.
+type ValidateConfigRequest struct {
+	Config json.RawMessage
+	Input  *schema.JSONSchema `json:",omitempty"`
+}
+
+// ValidateConfigResponse lists the invalid fields of the config, if any.
+// Output describes the messages the ability outputs with the valid config, see Satellite.DescribeOutput.
+// It is omitted if they are unknown.
+type ValidateConfigResponse struct {
+	Error  string                  `json:",omitempty"`
+	Errors schema.ValidationErrors `json:",omitempty"`
+	Output *schema.JSONSchema      `json:",omitempty"`
+}
+
+// StartTriggerRequest is a request to run a trigger with the config.
+// The trigger emits messages to Topic until it is stopped by StopTriggerRequest with the same ID.
+type StartTriggerRequest struct {
+	ID     string
+	Config json.RawMessage
+	Topic  string
+}
+
+// StopTriggerRequest is a request to stop the trigger started with the same ID.
+// It must be sent to the InstanceTopic of the satellite instance running the trigger.
+type StopTriggerRequest struct {
+	ID string
+}
+
+// TriggerResponse reports whether the trigger has been started or stopped
+// and by which satellite instance.
+type TriggerResponse struct {
+	Instance string
+	Error    string                  `json:",omitempty"`
+	Errors   schema.ValidationErrors `json:",omitempty"`
+}
+
+func makeDispatchEndpoint(s *Satellite, kind Kind, name string) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(DispatchRequest)
+		messages, err := s.Dispatch(ctx, kind, name, req.Config, req.Message)
+		if errs, ok := err.(schema.ValidationErrors); ok {
+			return DispatchResponse{Error: err.Error(), Errors: errs}, nil
+		}
+		if err != nil {
+			return DispatchResponse{Error: err.Error()}, nil
+		}
+		return DispatchResponse{Messages: messages}, nil
+	}
+}
+
+func makeStartTriggerEndpoint(s *Satellite, t Trigger) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		err := s.startTrigger(ctx, t, request.(StartTriggerRequest))
+		if errs, ok := err.(schema.ValidationErrors); ok {
+			return TriggerResponse{Instance: s.instance, Error: err.Error(), Errors: errs}, nil
+		}
+		if err != nil {
+			return TriggerResponse{Instance: s.instance, Error: err.Error()}, nil
+		}
+		return TriggerResponse{Instance: s.instance}, nil
+	}
+}
+
+func makeValidateConfigEndpoint(s *Satellite, kind Kind, name string) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(ValidateConfigRequest)
+		if req.Input != nil {
+			input, err := schema.FromJSONSchema(req.Input)
+			if err != nil {
+				return ValidateConfigResponse{Error: err.Error()}, nil
+			}
+			ctx = context.WithValue(ctx, ContextKeyInput, input)
+		}
+
+		output, err := s.DescribeOutput(ctx, kind, name, req.Config)
+		if errs, ok := err.(schema.ValidationErrors); ok {
+			return ValidateConfigResponse{Error: err.Error(), Errors: errs}, nil
+		}
+		if err != nil {
+			return ValidateConfigResponse{Error: err.Error()}, nil
+		}
+		if output == nil {
+			return ValidateConfigResponse{}, nil
+		}
+		return ValidateConfigResponse{Output: schema.ToJSONSchema(output)}, nil
+	}
+}
+
+func makeStopTriggerEndpoint(s *Satellite) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		err := s.stopTrigger(request.(StopTriggerRequest).ID)
+		if err != nil {
+			return TriggerResponse{Instance: s.instance, Error: err.Error()}, nil
+		}
+		return TriggerResponse{Instance: s.instance}, nil
+	}
+}
+
+func decodeJSONDispatchRequest(_ context.Context, data interface{}) (request interface{}, err error) {
+	var r DispatchRequest
+	err = json.Unmarshal(data.([]byte), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decodeJSONValidateConfigRequest(_ context.Context, data interface{}) (request interface{}, err error) {
+	var r ValidateConfigRequest
+	err = json.Unmarshal(data.([]byte), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decodeJSONStartTriggerRequest(_ context.Context, data interface{}) (request interface{}, err error) {
+	var r StartTriggerRequest
+	err = json.Unmarshal(data.([]byte), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func decodeJSONStopTriggerRequest(_ context.Context, data interface{}) (request interface{}, err error) {
+	var r StopTriggerRequest
+	err = json.Unmarshal(data.([]byte), &r)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func encodeJSONResponse(_ context.Context, data interface{}) (response interface{}, err error) {
+	var buf bytes.Buffer
+	err = json.NewEncoder(&buf).Encode(data)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
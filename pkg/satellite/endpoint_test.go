@@ -0,0 +1,26 @@
+package satellite
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/transport/redis"
+)
+
+func TestDecodeJSONRegisterResponse(t *testing.T) {
+	data := []byte(`{"Name":"cron","Version":"1","Description":"Ticks.","BrokenMissions":[{"ID":"m"}]}`)
+	res, err := decodeJSONRegisterResponse(context.Background(), data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i := res.(Info); i != (Info{Name: "cron", Version: "1", Description: "Ticks."}) {
+		t.Errorf("got %+v", i)
+	}
+
+	data = redis.DefaultErrorEncoder(context.Background(), errors.New("database is down")).([]byte)
+	res, err = decodeJSONRegisterResponse(context.Background(), data)
+	if err == nil || err.Error() != "database is down" {
+		t.Errorf("got %v, %v for an error response", res, err)
+	}
+}
@@ -1 +1,198 @@
 package fsevents
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/fsnotify/fsnotify"
+)
+
+// Types of files reported in the messages.
+const (
+	TypeFile      = "file"
+	TypeDirectory = "directory"
+)
+
+// WatchConfig is the config shared by the triggers.
+type WatchConfig struct {
+	Path      []string `json:"path" required:"true" min:"1" desc:"Paths to the files or directories."`
+	Recursive bool     `json:"recursive" desc:"Triggers on the events n-tiers down the directory tree."`
+	Include   string   `json:"include" desc:"Triggers only for the file names matching the pattern, e.g. *.log."`
+}
+
+type FileCreatedConfig struct {
+	WatchConfig
+}
+
+type FileModifiedConfig struct {
+	WatchConfig
+}
+
+type FileDeletedConfig struct {
+	WatchConfig
+}
+
+type FileRenamedConfig struct {
+	WatchConfig
+}
+
+// FileCreated emits a message when a new file or directory is created or moved to a watched directory.
+func FileCreated(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	return watch(ctx, config.(FileCreatedConfig).WatchConfig, fsnotify.Create, e)
+}
+
+// FileModified emits a message when a file is written to.
+func FileModified(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	return watch(ctx, config.(FileModifiedConfig).WatchConfig, fsnotify.Write, e)
+}
+
+// FileDeleted emits a message when a file or directory is deleted.
+func FileDeleted(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	return watch(ctx, config.(FileDeletedConfig).WatchConfig, fsnotify.Remove, e)
+}
+
+// FileRenamed emits a message when a file or directory is renamed or moved out of a watched directory.
+func FileRenamed(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	return watch(ctx, config.(FileRenamedConfig).WatchConfig, fsnotify.Rename, e)
+}
+
+// watch emits a message for every event of the operation until the context is canceled.
+func watch(ctx context.Context, c WatchConfig, op fsnotify.Op, e satellite.Emitter) error {
+	w, err := newWatcher(c.Path, c.Recursive)
+	if err != nil {
+		return err
+	}
+	defer w.close()
+
+	return w.run(ctx, func(ev event) error {
+		if ev.Op != op || !c.includes(ev.Path) {
+			return nil
+		}
+		return e.Emit(ctx, message(ev))
+	})
+}
+
+func (c WatchConfig) includes(path string) bool {
+	if c.Include == "" {
+		return true
+	}
+	ok, _ := filepath.Match(c.Include, filepath.Base(path))
+	return ok
+}
+
+func message(ev event) satellite.Message {
+	m := satellite.Message{"file": file(ev.Path, ev.Dir)}
+	if ev.To != "" {
+		m["to"] = file(ev.To, ev.Dir)
+	}
+	return m
+}
+
+func file(path string, dir bool) map[string]interface{} {
+	typ := TypeFile
+	if dir {
+		typ = TypeDirectory
+	}
+	return map[string]interface{}{
+		"name": filepath.Base(path),
+		"path": path,
+		"type": typ,
+	}
+}
+
+// ValidateWatch checks that the watched paths exist and that the include pattern is valid.
+func ValidateWatch(ctx context.Context, config interface{}) error {
+	var c WatchConfig
+	switch config := config.(type) {
+	case FileCreatedConfig:
+		c = config.WatchConfig
+	case FileModifiedConfig:
+		c = config.WatchConfig
+	case FileDeletedConfig:
+		c = config.WatchConfig
+	case FileRenamedConfig:
+		c = config.WatchConfig
+	}
+
+	var errs schema.ValidationErrors
+	for i, path := range c.Path {
+		if _, err := os.Stat(path); err != nil {
+			errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("path[%d]", i), Message: pathError(err)})
+		}
+	}
+	if _, err := filepath.Match(c.Include, ""); err != nil {
+		errs = append(errs, schema.ValidationError{Path: "include", Message: "is not a valid pattern"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// pathError describes an error returned by os.Stat.
+func pathError(err error) string {
+	switch {
+	case os.IsNotExist(err):
+		return "does not exist"
+	case os.IsPermission(err):
+		return "is not accessible"
+	}
+	return err.Error()
+}
+
+// FileFields describes a file in the messages.
+func FileFields(name, description string) *schema.Field {
+	return &schema.Field{
+		Name:        name,
+		Type:        schema.Object,
+		Description: description,
+		Required:    true,
+		Fields: schema.Fields{
+			"name": &schema.Field{
+				Name:        name + ".Name",
+				Type:        schema.String,
+				Description: "Name of the file or directory",
+				Required:    true,
+			},
+			"path": &schema.Field{
+				Name:        name + ".Path",
+				Type:        schema.String,
+				Description: "Absolute path to the file or directory",
+				Required:    true,
+			},
+			"type": &schema.Field{
+				Name:        name + ".Type",
+				Type:        schema.String,
+				Description: "Type of the object (file or directory)",
+				Required:    true,
+				Enum:        []interface{}{TypeFile, TypeDirectory},
+			},
+		},
+	}
+}
+
+func FileCreatedFields() schema.Fields {
+	return schema.Fields{"file": FileFields("File", "Created file or directory")}
+}
+
+func FileModifiedFields() schema.Fields {
+	return schema.Fields{"file": FileFields("File", "Modified file")}
+}
+
+func FileDeletedFields() schema.Fields {
+	return schema.Fields{"file": FileFields("File", "Deleted file or directory")}
+}
+
+func FileRenamedFields() schema.Fields {
+	to := FileFields("To", "Renamed file or directory, absent if it has been moved out of the watched directories")
+	to.Required = false
+	return schema.Fields{
+		"file": FileFields("File", "File or directory before it has been renamed"),
+		"to":   to,
+	}
+}
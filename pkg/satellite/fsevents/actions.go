@@ -0,0 +1,422 @@
+package fsevents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Default permissions of the created files and directories.
+const (
+	DefaultFileMode = 0644
+	DefaultDirMode  = 0755
+)
+
+// Paths and contents of the configs are templates of the message fields, see satellite.Template.
+
+type AppendFileConfig struct {
+	Path string `json:"path" required:"true" desc:"Path to the file, e.g. /var/log/{{service}}.log."`
+	Line string `json:"line" desc:"Appended line, e.g. {{issue.key}} {{issue.status}}. Defaults to the message as JSON."`
+	Mode string `json:"mode" pattern:"^0?[0-7]{3}$" default:"0644" desc:"Permissions of a new file."`
+	Sync bool   `json:"sync" desc:"Flushes the file to the disk before the next step."`
+}
+
+type WriteFileConfig struct {
+	Path      string `json:"path" required:"true" desc:"Path to the file."`
+	Content   string `json:"content" desc:"Content of the file. Defaults to the message as JSON."`
+	Mode      string `json:"mode" pattern:"^0?[0-7]{3}$" default:"0644" desc:"Permissions of the file."`
+	Overwrite bool   `json:"overwrite" desc:"Replaces an existing file."`
+	Sync      bool   `json:"sync" desc:"Flushes the file to the disk before the next step."`
+}
+
+type DeleteFileConfig struct {
+	Path      string `json:"path" required:"true" desc:"Path to the file or directory."`
+	Recursive bool   `json:"recursive" desc:"Deletes a directory with its content."`
+	MissingOK bool   `json:"missingOk" desc:"Succeeds if the file does not exist."`
+}
+
+type MoveFileConfig struct {
+	From      string `json:"from" required:"true" desc:"Path to the file or directory."`
+	To        string `json:"to" required:"true" desc:"New path to the file or directory."`
+	Overwrite bool   `json:"overwrite" desc:"Replaces an existing file."`
+	Sync      bool   `json:"sync" desc:"Flushes the directories to the disk before the next step."`
+}
+
+type MakeDirConfig struct {
+	Path    string `json:"path" required:"true" desc:"Path to the directory."`
+	Mode    string `json:"mode" pattern:"^0?[0-7]{3}$" default:"0755" desc:"Permissions of the directory."`
+	Parents bool   `json:"parents" desc:"Creates the missing parent directories."`
+}
+
+// fileActions change the files under the roots of the sandbox.
+type fileActions struct {
+	sandbox sandbox
+}
+
+// AppendFile appends a line to a file, creating the file if it does not exist.
+// The line is written at once, so that the lines appended concurrently do not interleave.
+func (a fileActions) AppendFile(
+	ctx context.Context,
+	config interface{},
+	m satellite.Message,
+) (satellite.Message, error) {
+	c := config.(AppendFileConfig)
+	path, err := a.path(c.Path, m, true)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := parseMode(c.Mode, DefaultFileMode)
+	if err != nil {
+		return nil, err
+	}
+	line, err := content(c.Line, m)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, mode)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write([]byte(line))
+	if err == nil && c.Sync {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"file": file(path, false)}, nil
+}
+
+// WriteFile creates or overwrites a file atomically: the content is written to a temporary file,
+// which is then renamed, so that the readers never see a partially written file.
+func (a fileActions) WriteFile(
+	ctx context.Context,
+	config interface{},
+	m satellite.Message,
+) (satellite.Message, error) {
+	c := config.(WriteFileConfig)
+	path, err := a.path(c.Path, m, false)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := parseMode(c.Mode, DefaultFileMode)
+	if err != nil {
+		return nil, err
+	}
+	data, err := content(c.Content, m)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeAtomic(path, []byte(data), mode, c.Overwrite, c.Sync)
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"file": file(path, false)}, nil
+}
+
+// DeleteFile deletes a file or a directory.
+func (a fileActions) DeleteFile(
+	ctx context.Context,
+	config interface{},
+	m satellite.Message,
+) (satellite.Message, error) {
+	c := config.(DeleteFileConfig)
+	path, err := a.path(c.Path, m, false)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) && c.MissingOK {
+		return satellite.Message{"file": file(path, false)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Recursive {
+		err = os.RemoveAll(path)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"file": file(path, info.IsDir())}, nil
+}
+
+// MoveFile renames a file or a directory. Both paths must be on the same file system.
+func (a fileActions) MoveFile(
+	ctx context.Context,
+	config interface{},
+	m satellite.Message,
+) (satellite.Message, error) {
+	c := config.(MoveFileConfig)
+	from, err := a.path(c.From, m, false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := a.path(c.To, m, false)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Lstat(from)
+	if err != nil {
+		return nil, err
+	}
+	if c.Overwrite {
+		err = os.Rename(from, to)
+	} else {
+		err = moveNoReplace(from, to, info.IsDir())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Sync {
+		err = syncDir(filepath.Dir(from))
+		if err == nil && filepath.Dir(to) != filepath.Dir(from) {
+			err = syncDir(filepath.Dir(to))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return satellite.Message{"file": file(to, info.IsDir()), "from": file(from, info.IsDir())}, nil
+}
+
+// MakeDir creates a directory. It succeeds if the directory already exists.
+func (a fileActions) MakeDir(
+	ctx context.Context,
+	config interface{},
+	m satellite.Message,
+) (satellite.Message, error) {
+	c := config.(MakeDirConfig)
+	path, err := a.path(c.Path, m, false)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := parseMode(c.Mode, DefaultDirMode)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Parents {
+		err = os.MkdirAll(path, mode)
+	} else {
+		err = os.Mkdir(path, mode)
+	}
+	if os.IsExist(err) {
+		if info, serr := os.Stat(path); serr == nil && info.IsDir() {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"file": file(path, true)}, nil
+}
+
+// path renders the path template and resolves the path within the sandbox.
+func (a fileActions) path(t string, m satellite.Message, follow bool) (string, error) {
+	path, err := satellite.Template(t).Render(m)
+	if err != nil {
+		return "", err
+	}
+	return a.sandbox.resolve(path, follow)
+}
+
+// content renders the template, an empty template renders the message as JSON.
+func content(t string, m satellite.Message) (string, error) {
+	if t == "" {
+		data, err := json.Marshal(m)
+		return string(data), err
+	}
+	return satellite.Template(t).Render(m)
+}
+
+// parseMode parses octal permissions, e.g. "0644".
+func parseMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// writeAtomic writes the data to a temporary file in the same directory and renames it to the path.
+// Unless overwrite is true, it fails if the path exists. If sync is true, the file
+// and the directory are flushed to the disk, so that the file survives a power loss.
+func writeAtomic(path string, data []byte, mode os.FileMode, overwrite, sync bool) (err error) {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		return err
+	}
+	err = tmp.Chmod(mode)
+	if err != nil {
+		return err
+	}
+	if sync {
+		err = tmp.Sync()
+		if err != nil {
+			return err
+		}
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	if overwrite {
+		err = os.Rename(tmp.Name(), path)
+	} else {
+		// Unlike rename, link fails if the path exists.
+		err = os.Link(tmp.Name(), path)
+		if err == nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if sync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// moveNoReplace renames the file or the directory, it fails if the new path exists.
+// Checking the new path before the rename would race with the files created in between.
+//
+// A file is linked to the new path, which fails if the path exists, and then unlinked
+// from the old one. A directory cannot be linked, an empty directory is created
+// in its place instead, which fails if the path exists, and the directory is renamed over it.
+// If anything is put into the empty directory in the meantime, the rename fails.
+func moveNoReplace(from, to string, dir bool) error {
+	if !dir {
+		err := os.Link(from, to)
+		if err != nil {
+			return err
+		}
+		return os.Remove(from)
+	}
+
+	err := os.Mkdir(to, 0700)
+	if err != nil {
+		return err
+	}
+	// Unlike syscall.Rename, os.Rename refuses to replace a directory.
+	err = syscall.Rename(from, to)
+	if err != nil {
+		os.Remove(to)
+		return &os.LinkError{Op: "rename", Old: from, New: to, Err: err}
+	}
+	return nil
+}
+
+// syncDir flushes the directory entries to the disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if cerr := d.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// validate checks the templates, the constant paths and the mode of an action config.
+func (a fileActions) validate(paths map[string]string, templates map[string]string, mode string) error {
+	var errs schema.ValidationErrors
+	for key, p := range paths {
+		t := satellite.Template(p)
+		if _, err := t.Fields(); err != nil {
+			errs = append(errs, schema.ValidationError{Path: key, Message: err.Error()})
+			continue
+		}
+		if !t.IsConstant() {
+			continue
+		}
+		if _, err := a.sandbox.resolve(p, false); err != nil {
+			errs = append(errs, schema.ValidationError{Path: key, Message: err.Error()})
+		}
+	}
+	for key, t := range templates {
+		if _, err := satellite.Template(t).Fields(); err != nil {
+			errs = append(errs, schema.ValidationError{Path: key, Message: err.Error()})
+		}
+	}
+	if _, err := parseMode(mode, 0); err != nil {
+		errs = append(errs, schema.ValidationError{Path: "mode", Message: err.Error()})
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+		return errs
+	}
+	return nil
+}
+
+// Validate checks the config of any file action.
+func (a fileActions) Validate(ctx context.Context, config interface{}) error {
+	switch c := config.(type) {
+	case AppendFileConfig:
+		return a.validate(map[string]string{"path": c.Path}, map[string]string{"line": c.Line}, c.Mode)
+	case WriteFileConfig:
+		return a.validate(map[string]string{"path": c.Path}, map[string]string{"content": c.Content}, c.Mode)
+	case DeleteFileConfig:
+		return a.validate(map[string]string{"path": c.Path}, nil, "")
+	case MoveFileConfig:
+		return a.validate(map[string]string{"from": c.From, "to": c.To}, nil, "")
+	case MakeDirConfig:
+		return a.validate(map[string]string{"path": c.Path}, nil, c.Mode)
+	}
+	return nil
+}
+
+// ActionFields describes the messages returned by the actions.
+func ActionFields(description string) schema.Fields {
+	return schema.Fields{"file": FileFields("File", description)}
+}
+
+func MoveFileFields() schema.Fields {
+	f := ActionFields("Moved file or directory")
+	f["from"] = FileFields("From", "File or directory before it has been moved")
+	return f
+}
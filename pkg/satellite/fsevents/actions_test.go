@@ -0,0 +1,95 @@
+package fsevents
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveNoReplace(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	path := func(name string) string { return filepath.Join(root, name) }
+
+	for _, name := range []string{"a", "taken"} {
+		err := ioutil.WriteFile(path(name), []byte(name), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"dir", "full", "empty"} {
+		err := os.Mkdir(path(name), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := ioutil.WriteFile(path("dir/f"), []byte("f"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(path("full/f"), []byte("f"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		dir      bool
+		exists   bool
+	}{
+		{"a", "taken", false, true},
+		{"a", "empty", false, true},
+		{"dir", "taken", true, true},
+		{"dir", "full", true, true},
+		{"dir", "empty", true, true},
+		{"a", "b", false, false},
+		{"dir", "moved", true, false},
+	}
+	for _, tt := range tests {
+		err := moveNoReplace(path(tt.from), path(tt.to), tt.dir)
+		if tt.exists {
+			if !os.IsExist(err) {
+				t.Errorf("%s to %s: got %v, want an existing file error", tt.from, tt.to, err)
+			}
+			if _, err := os.Lstat(path(tt.from)); err != nil {
+				t.Errorf("%s to %s: %v", tt.from, tt.to, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s to %s: %v", tt.from, tt.to, err)
+			continue
+		}
+		if _, err := os.Lstat(path(tt.from)); !os.IsNotExist(err) {
+			t.Errorf("%s to %s: the old path is left, %v", tt.from, tt.to, err)
+		}
+	}
+
+	if data, err := ioutil.ReadFile(path("b")); string(data) != "a" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if data, err := ioutil.ReadFile(path("moved/f")); string(data) != "f" {
+		t.Errorf("got %q, %v", data, err)
+	}
+	if data, err := ioutil.ReadFile(path("taken")); string(data) != "taken" {
+		t.Errorf("got %q, %v, the existing file is replaced", data, err)
+	}
+}
+
+func TestMoveNoReplaceSymlink(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+
+	err := os.Symlink("missing", filepath.Join(root, "link"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = moveNoReplace(filepath.Join(root, "link"), filepath.Join(root, "moved"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, err := os.Readlink(filepath.Join(root, "moved")); target != "missing" {
+		t.Errorf("got %q, %v", target, err)
+	}
+}
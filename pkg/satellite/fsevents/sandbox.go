@@ -0,0 +1,100 @@
+package fsevents
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOutsideRoots is returned when an action is asked to change a path outside of the allowed roots.
+var ErrOutsideRoots = errors.New("path is outside of the allowed roots")
+
+// sandbox confines the actions to the paths under its roots.
+type sandbox struct {
+	roots []string
+}
+
+// resolve returns the absolute path, the symbolic links of which are evaluated,
+// if it is under one of the roots and ErrOutsideRoots otherwise. The last element
+// of the path is evaluated only if follow is true, e.g. a symbolic link itself is deleted,
+// but the file it links to is appended. The roots themselves are not allowed,
+// so that they cannot be deleted or moved.
+func (s sandbox) resolve(path string, follow bool) (string, error) {
+	if path == "" {
+		return "", errors.New("path is empty")
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	dir, err := evalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	real := filepath.Join(dir, filepath.Base(path))
+	if follow {
+		real, err = evalSymlinks(real)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, root := range s.roots {
+		r, err := evalSymlinks(root)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(r, real)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return real, nil
+	}
+	return "", ErrOutsideRoots
+}
+
+// maxLinks limits the number of symbolic links followed by evalSymlinks.
+const maxLinks = 255
+
+// evalSymlinks is like filepath.EvalSymlinks, but the path may not exist.
+// The symbolic links of its longest existing prefix are evaluated,
+// a link to a path that does not exist is followed too.
+func evalSymlinks(path string) (string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	for links := 0; links < maxLinks; {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		if info, lerr := os.Lstat(path); lerr == nil && info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(path)
+			if err != nil {
+				return "", err
+			}
+			if !filepath.IsAbs(link) {
+				link = filepath.Join(filepath.Dir(path), link)
+			}
+			path = link
+			links++
+			continue
+		}
+
+		dir := filepath.Dir(path)
+		if dir == path {
+			return "", err
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = dir
+	}
+	return "", errors.New("too many links")
+}
@@ -0,0 +1,95 @@
+package fsevents
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandboxResolve(t *testing.T) {
+	root := tempDir(t)
+	defer os.RemoveAll(root)
+	outside := tempDir(t)
+	defer os.RemoveAll(outside)
+
+	err := os.Mkdir(filepath.Join(root, "dir"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("s"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		"out":      outside,
+		"secret":   filepath.Join(outside, "secret"),
+		"missing":  filepath.Join(outside, "missing"),
+		"relative": "../" + filepath.Base(outside),
+		"inside":   filepath.Join(root, "dir"),
+	}
+	for name, target := range links {
+		err = os.Symlink(target, filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := sandbox{roots: []string{root}}
+	tests := []struct {
+		path   string
+		follow bool
+		want   string
+	}{
+		{path: filepath.Join(root, "a.txt"), want: filepath.Join(root, "a.txt")},
+		{path: filepath.Join(root, "dir", "new", "a.txt"), want: filepath.Join(root, "dir", "new", "a.txt")},
+		{path: filepath.Join(root, "dir", "..", "a.txt"), want: filepath.Join(root, "a.txt")},
+		{path: filepath.Join(root, "inside", "a.txt"), want: filepath.Join(root, "dir", "a.txt")},
+		// The link itself is under the root, e.g. to be deleted.
+		{path: filepath.Join(root, "secret"), want: filepath.Join(root, "secret")},
+
+		{path: root},
+		{path: filepath.Join(root, "..")},
+		{path: root + "/../" + filepath.Base(outside) + "/a.txt"},
+		{path: filepath.Join(outside, "a.txt")},
+		{path: filepath.Join(root, "out", "a.txt")},
+		{path: filepath.Join(root, "relative", "a.txt")},
+		{path: filepath.Join(root, "secret"), follow: true},
+		{path: filepath.Join(root, "missing"), follow: true},
+	}
+	for _, tt := range tests {
+		got, err := s.resolve(tt.path, tt.follow)
+		if tt.want == "" {
+			if err != ErrOutsideRoots {
+				t.Errorf("resolve(%s, %v) = %s, %v, want ErrOutsideRoots", tt.path, tt.follow, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("resolve(%s, %v) = %s, %v, want %s", tt.path, tt.follow, got, err, tt.want)
+		}
+	}
+}
+
+func TestSandboxRootSymlink(t *testing.T) {
+	real := tempDir(t)
+	defer os.RemoveAll(real)
+	links := tempDir(t)
+	defer os.RemoveAll(links)
+
+	root := filepath.Join(links, "root")
+	err := os.Symlink(real, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := sandbox{roots: []string{root}}
+	got, err := s.resolve(filepath.Join(root, "a.txt"), false)
+	if err != nil || got != filepath.Join(real, "a.txt") {
+		t.Errorf("got %s, %v", got, err)
+	}
+	_, err = s.resolve(filepath.Join(links, "a.txt"), false)
+	if err != ErrOutsideRoots {
+		t.Errorf("got %v, want ErrOutsideRoots", err)
+	}
+}
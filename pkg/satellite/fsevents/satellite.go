@@ -1 +1,145 @@
 package fsevents
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "File System Events",
+	Version:     "0.2.0-alpha",
+	Description: "Provides a mechanism for monitoring file system events.",
+}
+
+// Config configures the satellite, e.g. with GOGARIN_SATELLITE_FSEVENTS_ROOTS=/srv/data,/var/log/missions.
+type Config struct {
+	// Roots lists the directories, under which the actions may change files.
+	// Without roots, the actions fail with ErrOutsideRoots.
+	Roots []string
+}
+
+// New returns the satellite with all its abilities.
+func New(conn transport.Connection, c Config, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+	actions := fileActions{sandbox: sandbox{roots: c.Roots}}
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: FileCreated,
+			Info: satellite.AbilityInfo{
+				Name:        "File Created",
+				Description: "Triggers when a new file or directory is created.",
+			},
+			Config:    FileCreatedConfig{},
+			Output:    FileCreatedFields(),
+			Validator: ValidateWatch,
+		},
+	)
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: FileModified,
+			Info: satellite.AbilityInfo{
+				Name:        "File Modified",
+				Description: "Triggers when a file is written to.",
+			},
+			Config:    FileModifiedConfig{},
+			Output:    FileModifiedFields(),
+			Validator: ValidateWatch,
+		},
+	)
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: FileDeleted,
+			Info: satellite.AbilityInfo{
+				Name:        "File Deleted",
+				Description: "Triggers when a file or directory is deleted.",
+			},
+			Config:    FileDeletedConfig{},
+			Output:    FileDeletedFields(),
+			Validator: ValidateWatch,
+		},
+	)
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: FileRenamed,
+			Info: satellite.AbilityInfo{
+				Name:        "File Renamed",
+				Description: "Triggers when a file or directory is renamed or moved.",
+			},
+			Config:    FileRenamedConfig{},
+			Output:    FileRenamedFields(),
+			Validator: ValidateWatch,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: actions.AppendFile,
+			Info: satellite.AbilityInfo{
+				Name:        "Append file",
+				Description: "Appends a line to the file, creating the file if it does not exist.",
+			},
+			Config:    AppendFileConfig{},
+			Output:    ActionFields("Appended file"),
+			Validator: actions.Validate,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: actions.WriteFile,
+			Info: satellite.AbilityInfo{
+				Name:        "Write file",
+				Description: "Creates or overwrites the file atomically.",
+			},
+			Config:    WriteFileConfig{},
+			Output:    ActionFields("Written file"),
+			Validator: actions.Validate,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: actions.DeleteFile,
+			Info: satellite.AbilityInfo{
+				Name:        "Delete file",
+				Description: "Deletes the file or directory.",
+			},
+			Config:    DeleteFileConfig{},
+			Output:    ActionFields("Deleted file or directory"),
+			Validator: actions.Validate,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: actions.MoveFile,
+			Info: satellite.AbilityInfo{
+				Name:        "Move file",
+				Description: "Renames or moves the file or directory.",
+			},
+			Config:    MoveFileConfig{},
+			Output:    MoveFileFields(),
+			Validator: actions.Validate,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: actions.MakeDir,
+			Info: satellite.AbilityInfo{
+				Name:        "Make directory",
+				Description: "Creates the directory.",
+			},
+			Config:    MakeDirConfig{},
+			Output:    ActionFields("Created directory"),
+			Validator: actions.Validate,
+		},
+	)
+
+	return sat
+}
@@ -0,0 +1,266 @@
+package fsevents
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// renameTimeout is how long a rename waits for the event reporting the new name.
+// Both events are read from inotify at once, so they arrive back to back.
+const renameTimeout = 50 * time.Millisecond
+
+// event is a file system event. To is the new path of a renamed file,
+// it is empty if the file has been moved outside of the watched directories.
+type event struct {
+	Op   fsnotify.Op
+	Path string
+	Dir  bool
+	To   string
+}
+
+// watcher reports the file system events under the watched paths.
+// Unlike fsnotify.Watcher, it reports every event once, knows whether a removed path
+// was a directory, pairs the old and the new name of a renamed file and,
+// if recursive, watches the directories created under the watched ones.
+type watcher struct {
+	w         *fsnotify.Watcher
+	recursive bool
+	// watched holds the watched files and directories.
+	watched map[string]bool
+	// dirs holds the known directories, watched or not.
+	dirs map[string]bool
+	// gone holds the removed or renamed watched paths, inotify reports their
+	// removal twice: to the watch of the parent and to their own watch.
+	gone map[string]bool
+}
+
+func newWatcher(paths []string, recursive bool) (*watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		w:         fw,
+		recursive: recursive,
+		watched:   make(map[string]bool),
+		dirs:      make(map[string]bool),
+		gone:      make(map[string]bool),
+	}
+	for _, path := range paths {
+		path, err = filepath.Abs(path)
+		if err == nil {
+			err = w.add(path, nil)
+		}
+		if err != nil {
+			w.close()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// add watches the path. Directories are watched recursively if the watcher is recursive.
+// found, if not nil, is called for every file and directory found under the path.
+func (w *watcher) add(path string, found func(path string, dir bool)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	err = w.w.Add(path)
+	if err != nil {
+		return err
+	}
+	w.watched[path] = true
+	if !info.IsDir() {
+		return nil
+	}
+	w.dirs[path] = true
+
+	entries, err := readDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		p := filepath.Join(path, entry.Name())
+		if entry.IsDir() {
+			w.dirs[p] = true
+		}
+		if found != nil {
+			found(p, entry.IsDir())
+		}
+		if !entry.IsDir() || !w.recursive {
+			continue
+		}
+
+		err = w.add(p, found)
+		if os.IsNotExist(err) {
+			// Removed in the meantime.
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readDir(path string) ([]os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdir(-1)
+}
+
+// remove stops watching the path and the paths under it.
+func (w *watcher) remove(path string) {
+	prefix := path + string(filepath.Separator)
+	for p := range w.watched {
+		if p == path || len(p) > len(prefix) && p[:len(prefix)] == prefix {
+			w.w.Remove(p)
+			delete(w.watched, p)
+			w.gone[p] = true
+		}
+	}
+	for p := range w.dirs {
+		if p == path || len(p) > len(prefix) && p[:len(prefix)] == prefix {
+			delete(w.dirs, p)
+		}
+	}
+}
+
+func (w *watcher) close() error {
+	return w.w.Close()
+}
+
+// run calls handle for every event until the context is canceled,
+// a fatal error occurs or handle returns an error.
+func (w *watcher) run(ctx context.Context, handle func(event) error) error {
+	var (
+		rename  *event
+		timeout <-chan time.Time
+	)
+	flush := func() error {
+		if rename == nil {
+			return nil
+		}
+		e := *rename
+		rename, timeout = nil, nil
+		return handle(e)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err, ok := <-w.w.Errors:
+			if !ok {
+				return nil
+			}
+			if err == fsnotify.ErrEventOverflow {
+				// Some events are lost, but the watches are intact.
+				continue
+			}
+			return err
+
+		case <-timeout:
+			err := flush()
+			if err != nil {
+				return err
+			}
+
+		case fe, ok := <-w.w.Events:
+			if !ok {
+				return nil
+			}
+			if !w.watched[fe.Name] && !w.watched[filepath.Dir(fe.Name)] {
+				// Reported by a watch that has just been removed.
+				continue
+			}
+
+			if rename != nil && fe.Op&fsnotify.Create != 0 {
+				rename.To = fe.Name
+			}
+			err := flush()
+			if err != nil {
+				return err
+			}
+
+			events, werr := w.events(fe)
+			for i := range events {
+				if events[i].Op == fsnotify.Rename {
+					rename = &events[i]
+					timeout = time.After(renameTimeout)
+					continue
+				}
+				err = handle(events[i])
+				if err != nil {
+					return err
+				}
+			}
+			if werr != nil {
+				return werr
+			}
+		}
+	}
+}
+
+// events converts the fsnotify.Event to events, updating the watches.
+// A single fsnotify.Event may carry many operations. Attribute changes are ignored.
+// It returns an error along with the events if a created directory cannot be watched,
+// e.g. because of the limit of inotify watches.
+func (w *watcher) events(fe fsnotify.Event) (events []event, err error) {
+	path := fe.Name
+
+	if fe.Op&fsnotify.Create != 0 {
+		delete(w.gone, path)
+		dir := false
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dir = true
+			w.dirs[path] = true
+		}
+		events = append(events, event{Op: fsnotify.Create, Path: path, Dir: dir})
+
+		if dir && w.recursive {
+			// Files created before the directory is watched are reported as created too.
+			err = w.add(path, func(p string, dir bool) {
+				events = append(events, event{Op: fsnotify.Create, Path: p, Dir: dir})
+			})
+			if os.IsNotExist(err) {
+				// Removed in the meantime.
+				err = nil
+			}
+		}
+	}
+
+	if fe.Op&fsnotify.Write != 0 {
+		events = append(events, event{Op: fsnotify.Write, Path: path, Dir: w.dirs[path]})
+	}
+
+	for _, op := range []fsnotify.Op{fsnotify.Remove, fsnotify.Rename} {
+		if fe.Op&op == 0 {
+			continue
+		}
+		if w.gone[path] {
+			delete(w.gone, path)
+			continue
+		}
+
+		dir := w.dirs[path]
+		if w.watched[path] {
+			w.remove(path)
+		} else {
+			delete(w.dirs, path)
+		}
+		events = append(events, event{Op: op, Path: path, Dir: dir})
+	}
+	return events, err
+}
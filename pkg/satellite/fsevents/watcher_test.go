@@ -0,0 +1,205 @@
+package fsevents
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// startWatcher runs a watcher of the paths and returns the channel of its events.
+func startWatcher(t *testing.T, recursive bool, paths ...string) (<-chan event, func()) {
+	w, err := newWatcher(paths, recursive)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan event, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.run(ctx, func(e event) error {
+			events <- e
+			return nil
+		})
+	}()
+
+	return events, func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+		w.close()
+	}
+}
+
+// expectEvent waits for the next event of the operation, skipping the others, e.g. writes reported on create.
+func expectEvent(t *testing.T, events <-chan event, op fsnotify.Op) event {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-events:
+			if e.Op == op {
+				return e
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", op)
+			return event{}
+		}
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "fsevents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestWatcherCreateWriteRemove(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	events, stop := startWatcher(t, false, dir)
+	defer stop()
+
+	path := filepath.Join(dir, "a.txt")
+	err := ioutil.WriteFile(path, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := expectEvent(t, events, fsnotify.Create)
+	if e.Path != path || e.Dir {
+		t.Errorf("create: got %+v", e)
+	}
+	e = expectEvent(t, events, fsnotify.Write)
+	if e.Path != path {
+		t.Errorf("write: got %+v", e)
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = expectEvent(t, events, fsnotify.Remove)
+	if e.Path != path || e.Dir {
+		t.Errorf("remove: got %+v", e)
+	}
+}
+
+func TestWatcherRename(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	outside := tempDir(t)
+	defer os.RemoveAll(outside)
+
+	from := filepath.Join(dir, "a.txt")
+	err := ioutil.WriteFile(from, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, stop := startWatcher(t, false, dir)
+	defer stop()
+
+	to := filepath.Join(dir, "b.txt")
+	err = os.Rename(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := expectEvent(t, events, fsnotify.Rename)
+	if e.Path != from || e.To != to {
+		t.Errorf("rename: got %+v", e)
+	}
+
+	gone := filepath.Join(outside, "b.txt")
+	err = os.Rename(to, gone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = expectEvent(t, events, fsnotify.Rename)
+	if e.Path != to || e.To != "" {
+		t.Errorf("move out: got %+v", e)
+	}
+}
+
+func TestWatcherRecursive(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	events, stop := startWatcher(t, true, dir)
+	defer stop()
+
+	sub := filepath.Join(dir, "sub")
+	err := os.Mkdir(sub, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := expectEvent(t, events, fsnotify.Create)
+	if e.Path != sub || !e.Dir {
+		t.Errorf("mkdir: got %+v", e)
+	}
+
+	// The new directory is watched once its creation has been handled.
+	time.Sleep(50 * time.Millisecond)
+	path := filepath.Join(sub, "a.txt")
+	err = ioutil.WriteFile(path, []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e = expectEvent(t, events, fsnotify.Create)
+	if e.Path != path {
+		t.Errorf("nested create: got %+v", e)
+	}
+
+	err = os.RemoveAll(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed := map[string]bool{}
+	for len(removed) < 2 {
+		e = expectEvent(t, events, fsnotify.Remove)
+		if removed[e.Path] {
+			t.Errorf("remove of %s reported twice", e.Path)
+		}
+		removed[e.Path] = true
+	}
+	if !removed[path] || !removed[sub] {
+		t.Errorf("remove: got %v", removed)
+	}
+}
+
+func TestWatcherNotRecursive(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	sub := filepath.Join(dir, "sub")
+	err := os.Mkdir(sub, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, stop := startWatcher(t, false, dir)
+	defer stop()
+
+	err = ioutil.WriteFile(filepath.Join(sub, "a.txt"), []byte("a"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := expectEvent(t, events, fsnotify.Create)
+	if e.Path != filepath.Join(dir, "b.txt") {
+		t.Errorf("got %+v, the nested file must not be reported", e)
+	}
+}
@@ -0,0 +1,128 @@
+package satellite
+
+import (
+	"encoding/json"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Registration describes the satellite and its abilities to the space center.
+type Registration struct {
+	Info      Info
+	Instance  string
+	Abilities []AbilityManifest
+}
+
+// AbilityManifest describes an ability, so that missions can be built from it.
+// Topic is the topic the ability receives requests from, see AbilityTopic.
+// ValidateTopic is the topic the ability validates configs on, see ValidateConfigTopic.
+// Input is empty for triggers.
+//
+// Config, Input and Output are encoded as JSON Schema, so that they can be consumed
+// by form generators and produced by satellites written in other languages.
+type AbilityManifest struct {
+	Kind          Kind
+	Name          string
+	Description   string
+	Topic         string
+	ValidateTopic string
+	Config        schema.Fields
+	Input         schema.Fields
+	Output        schema.Fields
+}
+
+type abilityManifestJSON struct {
+	Kind          Kind
+	Name          string
+	Description   string
+	Topic         string
+	ValidateTopic string             `json:",omitempty"`
+	Config        *schema.JSONSchema `json:",omitempty"`
+	Input         *schema.JSONSchema `json:",omitempty"`
+	Output        *schema.JSONSchema `json:",omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m AbilityManifest) MarshalJSON() ([]byte, error) {
+	toJSONSchema := func(f schema.Fields) *schema.JSONSchema {
+		if f == nil {
+			return nil
+		}
+		return schema.ToJSONSchema(f)
+	}
+
+	return json.Marshal(abilityManifestJSON{
+		Kind:          m.Kind,
+		Name:          m.Name,
+		Description:   m.Description,
+		Topic:         m.Topic,
+		ValidateTopic: m.ValidateTopic,
+		Config:        toJSONSchema(m.Config),
+		Input:         toJSONSchema(m.Input),
+		Output:        toJSONSchema(m.Output),
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *AbilityManifest) UnmarshalJSON(data []byte) error {
+	var j abilityManifestJSON
+	err := json.Unmarshal(data, &j)
+	if err != nil {
+		return err
+	}
+
+	fromJSONSchema := func(s *schema.JSONSchema) schema.Fields {
+		if s == nil || err != nil {
+			return nil
+		}
+		var f schema.Fields
+		f, err = schema.FromJSONSchema(s)
+		return f
+	}
+
+	*m = AbilityManifest{
+		Kind:          j.Kind,
+		Name:          j.Name,
+		Description:   j.Description,
+		Topic:         j.Topic,
+		ValidateTopic: j.ValidateTopic,
+		Config:        fromJSONSchema(j.Config),
+		Input:         fromJSONSchema(j.Input),
+		Output:        fromJSONSchema(j.Output),
+	}
+	return err
+}
+
+// Registration returns the satellite's Registration.
+func (s *Satellite) Registration() Registration {
+	r := Registration{Info: s.Info, Instance: s.instance}
+	add := func(kind Kind, info AbilityInfo, config, input, output schema.Fields) {
+		r.Abilities = append(r.Abilities, AbilityManifest{
+			Kind:          kind,
+			Name:          info.Name,
+			Description:   info.Description,
+			Topic:         AbilityTopic(s.Info.Name, kind, info.Name),
+			ValidateTopic: ValidateConfigTopic(s.Info.Name, kind, info.Name),
+			Config:        config,
+			Input:         input,
+			Output:        output,
+		})
+	}
+
+	for _, t := range s.Triggers {
+		add(KindTrigger, t.Info, t.ConfigFields, nil, t.Output)
+	}
+	for _, f := range s.Filters {
+		add(KindFilter, f.Info, f.ConfigFields, f.Input, f.Output)
+	}
+	for _, m := range s.Modifiers {
+		add(KindModifier, m.Info, m.ConfigFields, m.Input, m.Output)
+	}
+	for _, a := range s.Actions {
+		add(KindAction, a.Info, a.ConfigFields, a.Input, a.Output)
+	}
+	for _, sp := range s.Splitters {
+		add(KindSplitter, sp.Info, sp.ConfigFields, sp.Input, sp.Output)
+	}
+	return r
+}
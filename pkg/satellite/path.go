@@ -0,0 +1,88 @@
+package satellite
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Get returns the value of the field at the path, e.g. "issue.type" or "items[2].name".
+// Collections are indexed from zero. It reports whether the field is present.
+func (m Message) Get(path string) (interface{}, bool) {
+	var v interface{} = map[string]interface{}(m)
+	for _, token := range splitPath(path) {
+		var ok bool
+		v, ok = child(v, token)
+		if !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// pathToken is a key of an object or an index of a collection.
+type pathToken struct {
+	key   string
+	index int
+}
+
+// splitPath splits "items[2].name" into "items", 2 and "name".
+// An invalid index is treated as a key, so that it does not match.
+func splitPath(path string) []pathToken {
+	var tokens []pathToken
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indices []string
+		if i := strings.Index(part, "["); i >= 0 && strings.HasSuffix(part, "]") {
+			key = part[:i]
+			indices = strings.Split(part[i+1:len(part)-1], "][")
+		}
+
+		if key != "" || len(indices) == 0 {
+			tokens = append(tokens, pathToken{key: key, index: -1})
+		}
+		for _, s := range indices {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				tokens = append(tokens, pathToken{key: "[" + s + "]", index: -1})
+				continue
+			}
+			tokens = append(tokens, pathToken{index: n})
+		}
+	}
+	return tokens
+}
+
+func child(v interface{}, t pathToken) (interface{}, bool) {
+	if t.index >= 0 {
+		switch items := v.(type) {
+		case []interface{}:
+			if t.index < len(items) {
+				return items[t.index], true
+			}
+			return nil, false
+		}
+		rv := reflect.ValueOf(v)
+		if (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && t.index < rv.Len() {
+			return rv.Index(t.index).Interface(), true
+		}
+		return nil, false
+	}
+
+	switch m := v.(type) {
+	case map[string]interface{}:
+		c, ok := m[t.key]
+		return c, ok
+	case Message:
+		c, ok := m[t.key]
+		return c, ok
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String {
+		c := rv.MapIndex(reflect.ValueOf(t.key).Convert(rv.Type().Key()))
+		if c.IsValid() {
+			return c.Interface(), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// ExecuteConfig lists the statements with the placeholders of message fields,
+// e.g. INSERT INTO issues (key, summary) VALUES ({{issue.key}}, {{issue.summary}}).
+type ExecuteConfig struct {
+	Connection string   `json:"connection" required:"true" secret:"true" desc:"Connection string."`
+	Statements []string `json:"statements" required:"true" min:"1" desc:"INSERT, UPDATE or DELETE statements."`
+}
+
+// Execute runs the statements in a transaction. Either all of them change the database or none.
+// It returns the number of the rows affected by the statements.
+func (p *Pools) Execute(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	c := config.(ExecuteConfig)
+	db, err := p.db(c.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var affected int64
+	for i, text := range c.Statements {
+		q, err := compile(text)
+		if err != nil {
+			return nil, fmt.Errorf("statements[%d]: %v", i, err)
+		}
+		args, err := q.args(m)
+		if err != nil {
+			return nil, fmt.Errorf("statements[%d]: %v", i, err)
+		}
+		res, err := tx.ExecContext(ctx, q.sql, args...)
+		if err != nil {
+			return nil, fmt.Errorf("statements[%d]: %v", i, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("statements[%d]: %v", i, err)
+		}
+		affected += n
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"rowsAffected": float64(affected)}, nil
+}
+
+// ExecuteFields describes the messages returned by Execute.
+func ExecuteFields() schema.Fields {
+	return schema.Fields{
+		"rowsAffected": {
+			Name:        "Rows Affected",
+			Type:        schema.Integer,
+			Description: "Number of the rows inserted, updated or deleted by the statements",
+			Required:    true,
+		},
+	}
+}
+
+// ValidateExecute checks the placeholders of the statements. If the incoming messages are known,
+// the placeholders must refer to their fields.
+func ValidateExecute(ctx context.Context, config interface{}) error {
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+
+	var errs schema.ValidationErrors
+	for i, text := range config.(ExecuteConfig).Statements {
+		errs = append(errs, validateQuery(fmt.Sprintf("statements[%d]", i), text, input)...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// QueryConfig is a SELECT query with the placeholders of message fields,
+// e.g. SELECT 1 FROM issues WHERE key = {{issue.key}}. The fields are bound as the query parameters.
+type QueryConfig struct {
+	Connection string `json:"connection" required:"true" secret:"true" desc:"Connection string."`
+	Query      string `json:"query" required:"true" desc:"SELECT query."`
+}
+
+// Exists passes the messages, for which the query returns a row.
+func (p *Pools) Exists(ctx context.Context, config interface{}, m satellite.Message) (bool, error) {
+	var ok bool
+	err := p.readOnly(ctx, config.(QueryConfig), m, func(rows *sql.Rows) error {
+		ok = rows.Next()
+		return rows.Err()
+	})
+	return ok, err
+}
+
+// readOnly runs the query in a read-only transaction, so that the filters and the modifiers
+// cannot change the database, and passes the returned rows to fn.
+func (p *Pools) readOnly(ctx context.Context, c QueryConfig, m satellite.Message, fn func(*sql.Rows) error) error {
+	q, err := compile(c.Query)
+	if err != nil {
+		return err
+	}
+	args, err := q.args(m)
+	if err != nil {
+		return err
+	}
+	db, err := p.db(c.Connection)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, q.sql, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return fn(rows)
+}
+
+// ValidateQuery checks the placeholders of the query. If the incoming messages are known,
+// the placeholders must refer to their fields.
+func ValidateQuery(ctx context.Context, config interface{}) error {
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+	errs := validateQuery("query", config.(QueryConfig).Query, input)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
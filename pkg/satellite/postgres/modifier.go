@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/lib/pq"
+)
+
+// SelectConfig is a query, the rows of which are added to the Field of the message,
+// e.g. SELECT name, email FROM users WHERE login = {{issue.assignee}}.
+type SelectConfig struct {
+	QueryConfig
+	Field  string `json:"field" required:"true" examples:"rows" desc:"Field the rows are added to."`
+	Single bool   `json:"single" desc:"Adds the first row, or null, instead of a collection of the rows."`
+}
+
+// Select adds the rows returned by the query to the field of the message.
+// The columns are converted to the field types, see DescribeSelect.
+func (p *Pools) Select(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	c := config.(SelectConfig)
+
+	var rows []map[string]interface{}
+	err := p.readOnly(ctx, c.QueryConfig, m, func(r *sql.Rows) error {
+		var err error
+		rows, err = scanRows(r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(satellite.Message, len(m)+1)
+	for key, v := range m {
+		res[key] = v
+	}
+	switch {
+	case c.Single && len(rows) == 0:
+		res[c.Field] = nil
+	case c.Single:
+		res[c.Field] = rows[0]
+	default:
+		items := make([]interface{}, len(rows))
+		for i, row := range rows {
+			items[i] = row
+		}
+		res[c.Field] = items
+	}
+	return res, nil
+}
+
+// ValidateSelect checks the placeholders of the query and the field.
+func ValidateSelect(ctx context.Context, config interface{}) error {
+	c := config.(SelectConfig)
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+
+	errs := validateQuery("query", c.Query, input)
+	if strings.ContainsAny(c.Field, ".[]") {
+		errs = append(errs, schema.ValidationError{Path: "field", Message: "must be a key of the message, not a path"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// DescribeSelect adds the field of the rows to the incoming messages. The columns are described
+// by running the query with null parameters and without rows. Integers are described as Integer,
+// floating point and numeric as Float, booleans as Boolean, dates as Date, timestamps as Datetime,
+// bytea as a base64 encoded String and the rest as String.
+// The outgoing messages are unknown if the incoming ones are, or if the database cannot be reached.
+func (p *Pools) DescribeSelect(ctx context.Context, config interface{}, input schema.Fields) (schema.Fields, error) {
+	c := config.(SelectConfig)
+	if input == nil {
+		return nil, nil
+	}
+
+	columns, err := p.columns(ctx, c.QueryConfig)
+	if err != nil {
+		return nil, schema.ValidationErrors{{Path: "query", Message: err.Error()}}
+	}
+	if columns == nil {
+		return nil, nil
+	}
+
+	output := make(schema.Fields, len(input)+1)
+	for key, f := range input {
+		output[key] = f
+	}
+	row := &schema.Field{Name: c.Field, Type: schema.Object, Required: true, Fields: columns}
+	if c.Single {
+		row.Nullable = true
+		output[c.Field] = row
+	} else {
+		output[c.Field] = &schema.Field{Name: c.Field, Type: schema.Collection, Required: true, Items: row}
+	}
+	return output, nil
+}
+
+// columns describes the columns returned by the query. They are nil if the database cannot be reached,
+// or if the types of the parameters cannot be determined without their values.
+func (p *Pools) columns(ctx context.Context, c QueryConfig) (schema.Fields, error) {
+	q, err := compile(c.Query)
+	if err != nil {
+		return nil, err
+	}
+	db, err := p.db(c.Connection)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	if db.PingContext(ctx) != nil {
+		return nil, nil
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil
+	}
+	defer tx.Rollback()
+
+	text := fmt.Sprintf("SELECT * FROM (%s) AS q LIMIT 0", strings.TrimRight(q.sql, "; \t\r\n"))
+	rows, err := tx.QueryContext(ctx, text, make([]interface{}, len(q.paths))...)
+	if e, ok := err.(*pq.Error); ok && e.Code.Name() == "indeterminate_datatype" {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := make(schema.Fields, len(types))
+	for _, t := range types {
+		columns[t.Name()] = columnField(t.Name(), t.DatabaseTypeName())
+	}
+	return columns, nil
+}
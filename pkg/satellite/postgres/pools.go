@@ -0,0 +1,90 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	// Registers the postgres driver.
+	_ "github.com/lib/pq"
+)
+
+const driver = "postgres"
+
+// Pools keeps a connection pool per connection string of the ability configs,
+// so that the steps connecting to the same database share the pool.
+// The pools unused for longer than the idle timeout are closed, see Config.PoolIdleTimeoutInMs.
+type Pools struct {
+	c Config
+
+	mu  sync.Mutex
+	dbs map[string]*pool
+}
+
+type pool struct {
+	db   *sql.DB
+	used time.Time
+}
+
+// NewPools returns the pools limited by the config. They must be closed, see Close.
+func NewPools(c Config) *Pools {
+	return &Pools{c: c, dbs: map[string]*pool{}}
+}
+
+// db returns the pool of the connection string, opening it on the first use.
+// It closes the idle pools of the other connection strings.
+func (p *Pools) db(connection string) (*sql.DB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.evict(now)
+	if e, ok := p.dbs[connection]; ok {
+		e.used = now
+		return e.db, nil
+	}
+	db, err := sql.Open(driver, connection)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(p.c.MaxOpenConns)
+	db.SetMaxIdleConns(p.c.MaxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(p.c.ConnMaxLifetimeInMs) * time.Millisecond)
+	p.dbs[connection] = &pool{db: db, used: now}
+	return db, nil
+}
+
+// evict closes the pools unused for longer than the idle timeout.
+func (p *Pools) evict(now time.Time) {
+	idle := p.c.PoolIdleTimeoutInMs
+	if idle < p.c.QueryTimeoutInMs {
+		idle = p.c.QueryTimeoutInMs
+	}
+	for connection, e := range p.dbs {
+		if now.Sub(e.used) > time.Duration(idle)*time.Millisecond {
+			e.db.Close() // nolint: errcheck
+			delete(p.dbs, connection)
+		}
+	}
+}
+
+// withTimeout limits the context by the query timeout of the config.
+func (p *Pools) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, time.Duration(p.c.QueryTimeoutInMs)*time.Millisecond)
+}
+
+// Close closes the pools. It returns the first error.
+func (p *Pools) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	for connection, pl := range p.dbs {
+		if e := pl.db.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(p.dbs, connection)
+	}
+	return err
+}
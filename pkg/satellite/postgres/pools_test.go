@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoolsEvict(t *testing.T) {
+	p := NewPools(Config{PoolIdleTimeoutInMs: 1000, QueryTimeoutInMs: 100})
+	defer p.Close()
+
+	a, err := p.db("postgres://localhost/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = p.db("postgres://localhost/b"); err != nil {
+		t.Fatal(err)
+	}
+	p.dbs["postgres://localhost/b"].used = time.Now().Add(-2 * time.Second)
+
+	again, err := p.db("postgres://localhost/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != a {
+		t.Error("the pool in use has been reopened")
+	}
+	if _, ok := p.dbs["postgres://localhost/b"]; ok {
+		t.Error("the idle pool has not been closed")
+	}
+}
+
+func TestPoolsEvictAfterQueryTimeout(t *testing.T) {
+	p := NewPools(Config{PoolIdleTimeoutInMs: 10, QueryTimeoutInMs: 5000})
+	defer p.Close()
+
+	if _, err := p.db("postgres://localhost/a"); err != nil {
+		t.Fatal(err)
+	}
+	p.dbs["postgres://localhost/a"].used = time.Now().Add(-time.Second)
+	if _, err := p.db("postgres://localhost/b"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.dbs["postgres://localhost/a"]; !ok {
+		t.Error("the pool has been closed before its queries could time out")
+	}
+}
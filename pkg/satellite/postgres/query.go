@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/expr"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// query is SQL with the placeholders of message fields replaced by the positional parameters,
+// e.g. "WHERE key = {{issue.key}}" is "WHERE key = $1" with "issue.key" bound to $1.
+// The values are never interpolated into the SQL.
+type query struct {
+	sql   string
+	paths []string
+	// quoted lists the placeholders within the string literals, the quoted identifiers
+	// and the comments, which are left as they are.
+	quoted []string
+}
+
+// dollarTag matches the opening tag of a dollar-quoted string, e.g. $$ or $body$.
+var dollarTag = regexp.MustCompile(`^\$([A-Za-z_\x80-\x{10FFFF}][A-Za-z0-9_\x80-\x{10FFFF}]*)?\$`)
+
+// compile replaces the placeholders of the message fields with the positional parameters.
+// The same field is bound to the same parameter.
+func compile(text string) (query, error) {
+	var q query
+	var b bytes.Buffer
+	params := map[string]int{}
+	for i := 0; i < len(text); {
+		var prev byte
+		if i > 0 {
+			prev = text[i-1]
+		}
+		if n := quotedLen(text[i:], prev); n > 0 {
+			q.quoted = append(q.quoted, placeholders(text[i:i+n])...)
+			b.WriteString(text[i : i+n])
+			i += n
+			continue
+		}
+		if !strings.HasPrefix(text[i:], satellite.TemplateOpen) {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+
+		s := text[i+len(satellite.TemplateOpen):]
+		j := strings.Index(s, satellite.TemplateClose)
+		if j < 0 {
+			return query{}, satellite.ErrUnclosedPlaceholder
+		}
+		path := strings.TrimSpace(s[:j])
+		if path == "" {
+			return query{}, fmt.Errorf("empty placeholder")
+		}
+		i += len(satellite.TemplateOpen) + j + len(satellite.TemplateClose)
+
+		n, ok := params[path]
+		if !ok {
+			q.paths = append(q.paths, path)
+			n = len(q.paths)
+			params[path] = n
+		}
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	q.sql = b.String()
+	return q, nil
+}
+
+// quotedLen returns the length of the string literal, the quoted identifier or the comment
+// the SQL starts with, or 0 if it starts with none of them. prev is the preceding byte, if any,
+// e.g. E of an E'...' string with backslash escapes. An unterminated one lasts to the end.
+func quotedLen(sql string, prev byte) int {
+	switch {
+	case strings.HasPrefix(sql, "--"):
+		if i := strings.IndexByte(sql, '\n'); i >= 0 {
+			return i + 1
+		}
+		return len(sql)
+
+	case strings.HasPrefix(sql, "/*"):
+		// Block comments nest.
+		depth := 0
+		for i := 0; i+1 < len(sql); i++ {
+			switch sql[i : i+2] {
+			case "/*":
+				depth++
+				i++
+			case "*/":
+				depth--
+				i++
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return len(sql)
+
+	case sql[0] == '\'' || sql[0] == '"':
+		quote := sql[0]
+		escapes := quote == '\'' && (prev == 'E' || prev == 'e')
+		for i := 1; i < len(sql); i++ {
+			switch {
+			case escapes && sql[i] == '\\':
+				i++
+			case sql[i] == quote:
+				// A doubled quote stands for itself.
+				if i+1 < len(sql) && sql[i+1] == quote {
+					i++
+					continue
+				}
+				return i + 1
+			}
+		}
+		return len(sql)
+
+	case sql[0] == '$' && !isIdentByte(prev):
+		tag := dollarTag.FindString(sql)
+		if tag == "" {
+			return 0
+		}
+		if i := strings.Index(sql[len(tag):], tag); i >= 0 {
+			return len(tag) + i + len(tag)
+		}
+		return len(sql)
+	}
+	return 0
+}
+
+// isIdentByte reports whether the byte may be a part of an identifier, e.g. of a$1.
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// placeholders returns the placeholders within the text, which are not replaced.
+func placeholders(text string) []string {
+	var found []string
+	for {
+		i := strings.Index(text, satellite.TemplateOpen)
+		if i < 0 {
+			return found
+		}
+		text = text[i:]
+		j := strings.Index(text, satellite.TemplateClose)
+		if j < 0 {
+			return append(found, text)
+		}
+		found = append(found, text[:j+len(satellite.TemplateClose)])
+		text = text[j+len(satellite.TemplateClose):]
+	}
+}
+
+// args returns the values of the parameters. Absent fields are null,
+// objects and collections are bound as JSON, e.g. to json or jsonb columns.
+func (q query) args(m satellite.Message) ([]interface{}, error) {
+	args := make([]interface{}, len(q.paths))
+	for i, path := range q.paths {
+		v, _ := m.Get(path)
+		switch v.(type) {
+		case map[string]interface{}, satellite.Message, []interface{}:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = string(data)
+		default:
+			args[i] = v
+		}
+	}
+	return args, nil
+}
+
+// validateQuery checks the placeholders of the query at the path of the config.
+// If the incoming messages are known, the placeholders must refer to their fields.
+func validateQuery(path, text string, input schema.Fields) schema.ValidationErrors {
+	q, err := compile(text)
+	if err != nil {
+		return schema.ValidationErrors{{Path: path, Message: err.Error()}}
+	}
+
+	var errs schema.ValidationErrors
+	for _, p := range q.quoted {
+		errs = append(errs, schema.ValidationError{
+			Path:    path,
+			Message: p + " is within a string literal or a comment, it is not replaced",
+		})
+	}
+	if input == nil {
+		return errs
+	}
+	for _, p := range q.paths {
+		if _, ok := expr.Lookup(input, p); !ok {
+			errs = append(errs, schema.ValidationError{Path: path, Message: "unknown field {{" + p + "}}"})
+		}
+	}
+	return errs
+}
+
+// scanRows returns the rows as objects keyed by the column names, see value.
+func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, c := range columns {
+			row[c.Name()], err = value(c.DatabaseTypeName(), values[i])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", c.Name(), err)
+			}
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+// value converts a value of the column type to a value of a message described by columnField.
+func value(typ string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), nil
+	case time.Time:
+		switch typ {
+		case "DATE":
+			return v.Format(schema.DateLayout), nil
+		case "TIME", "TIMETZ":
+			return v.Format("15:04:05"), nil
+		}
+		return v.Format(schema.DatetimeLayout), nil
+	case []byte:
+		switch typ {
+		case "BYTEA":
+			return base64.StdEncoding.EncodeToString(v), nil
+		case "NUMERIC":
+			return strconv.ParseFloat(string(v), 64)
+		}
+		return string(v), nil
+	}
+	return v, nil
+}
+
+// columnField describes the values of the column type, see value.
+// Bytea is described as a base64 encoded String, JSON as a String.
+func columnField(name, typ string) *schema.Field {
+	f := &schema.Field{Name: name, Type: schema.String, Required: true, Nullable: true}
+	switch typ {
+	case "BOOL":
+		f.Type = schema.Boolean
+	case "INT2", "INT4", "INT8", "OID":
+		f.Type = schema.Integer
+	case "FLOAT4", "FLOAT8", "NUMERIC":
+		f.Type = schema.Float
+	case "DATE":
+		f.Type = schema.Date
+	case "TIMESTAMP", "TIMESTAMPTZ":
+		f.Type = schema.Datetime
+	}
+	return f
+}
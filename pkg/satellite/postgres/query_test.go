@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		text   string
+		sql    string
+		paths  []string
+		quoted []string
+	}{
+		{
+			text:  "SELECT 1 FROM issues WHERE key = {{issue.key}} AND project = {{ project }}",
+			sql:   "SELECT 1 FROM issues WHERE key = $1 AND project = $2",
+			paths: []string{"issue.key", "project"},
+		},
+		{
+			text:  "SELECT {{a}}, {{b}}, {{a}}",
+			sql:   "SELECT $1, $2, $1",
+			paths: []string{"a", "b"},
+		},
+		{
+			text:   "SELECT '{{a}}', 'it''s {{b}}', {{c}}",
+			sql:    "SELECT '{{a}}', 'it''s {{b}}', $1",
+			paths:  []string{"c"},
+			quoted: []string{"{{a}}", "{{b}}"},
+		},
+		{
+			text:   `SELECT E'\'{{a}}', {{b}}`,
+			sql:    `SELECT E'\'{{a}}', $1`,
+			paths:  []string{"b"},
+			quoted: []string{"{{a}}"},
+		},
+		{
+			text:   `SELECT "{{a}}" FROM t WHERE x = {{b}}`,
+			sql:    `SELECT "{{a}}" FROM t WHERE x = $1`,
+			paths:  []string{"b"},
+			quoted: []string{"{{a}}"},
+		},
+		{
+			text:   "SELECT $${{a}}$$, $fn$ $$ {{b}} $fn$, {{c}}",
+			sql:    "SELECT $${{a}}$$, $fn$ $$ {{b}} $fn$, $1",
+			paths:  []string{"c"},
+			quoted: []string{"{{a}}", "{{b}}"},
+		},
+		{
+			text:   "SELECT {{a}} -- {{b}}\nFROM t /* {{c}} /* {{d}} */ {{e}} */ WHERE x = {{f}}",
+			sql:    "SELECT $1 -- {{b}}\nFROM t /* {{c}} /* {{d}} */ {{e}} */ WHERE x = $2",
+			paths:  []string{"a", "f"},
+			quoted: []string{"{{b}}", "{{c}}", "{{d}}", "{{e}}"},
+		},
+		{
+			text:  "SELECT a$b$ FROM t WHERE x = {{a}} AND y = $b$",
+			sql:   "SELECT a$b$ FROM t WHERE x = $1 AND y = $b$",
+			paths: []string{"a"},
+		},
+		{
+			text:   "SELECT 1 -- {{a}}",
+			sql:    "SELECT 1 -- {{a}}",
+			quoted: []string{"{{a}}"},
+		},
+	}
+	for _, tt := range tests {
+		q, err := compile(tt.text)
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+		if q.sql != tt.sql {
+			t.Errorf("%s: got %s, want %s", tt.text, q.sql, tt.sql)
+		}
+		if !reflect.DeepEqual(q.paths, tt.paths) {
+			t.Errorf("%s: got paths %v, want %v", tt.text, q.paths, tt.paths)
+		}
+		if !reflect.DeepEqual(q.quoted, tt.quoted) {
+			t.Errorf("%s: got quoted %v, want %v", tt.text, q.quoted, tt.quoted)
+		}
+	}
+
+	for _, text := range []string{"SELECT {{a", "SELECT {{ }}"} {
+		if _, err := compile(text); err == nil {
+			t.Errorf("%s: no error", text)
+		}
+	}
+}
+
+func TestValidateQuery(t *testing.T) {
+	input := schema.Fields{"key": {Name: "Key", Type: schema.String}}
+
+	errs := validateQuery("query", "SELECT 1 FROM t WHERE key = {{key}}", input)
+	if len(errs) != 0 {
+		t.Errorf("got %v", errs)
+	}
+	errs = validateQuery("query", "SELECT 1 FROM t WHERE key = {{other}}", input)
+	if len(errs) != 1 || errs[0].Message != "unknown field {{other}}" {
+		t.Errorf("got %v", errs)
+	}
+	errs = validateQuery("query", "SELECT 1 FROM t WHERE key = '{{key}}'", nil)
+	if len(errs) != 1 || errs[0].Path != "query" {
+		t.Errorf("got %v", errs)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	q, err := compile("{{key}} {{points}} {{labels}} {{issue}} {{missing}} {{issue.key}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := satellite.Message{
+		"key":    "A-1",
+		"points": 3.0,
+		"labels": []interface{}{"a", "b"},
+		"issue":  map[string]interface{}{"key": "A-2"},
+	}
+	got, err := q.args(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"A-1", 3.0, `["a","b"]`, `{"key":"A-2"}`, nil, "A-2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestValue(t *testing.T) {
+	ts := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		typ  string
+		v    interface{}
+		want interface{}
+	}{
+		{typ: "INT8", v: int64(42), want: 42.0},
+		{typ: "FLOAT8", v: 2.5, want: 2.5},
+		{typ: "BOOL", v: true, want: true},
+		{typ: "TEXT", v: "a", want: "a"},
+		{typ: "TEXT", v: nil, want: nil},
+		{typ: "DATE", v: ts, want: "2020-01-02"},
+		{typ: "TIME", v: ts, want: "03:04:05"},
+		{typ: "TIMESTAMPTZ", v: ts, want: ts.Format(schema.DatetimeLayout)},
+		{typ: "BYTEA", v: []byte("ab"), want: "YWI="},
+		{typ: "NUMERIC", v: []byte("1.25"), want: 1.25},
+		{typ: "JSONB", v: []byte(`{"a":1}`), want: `{"a":1}`},
+	}
+	for _, tt := range tests {
+		got, err := value(tt.typ, tt.v)
+		if err != nil || got != tt.want {
+			t.Errorf("value(%s, %v) = %#v, %v, want %#v", tt.typ, tt.v, got, err, tt.want)
+		}
+	}
+
+	if _, err := value("NUMERIC", []byte("NaN?")); err == nil {
+		t.Error("invalid NUMERIC: no error")
+	}
+}
+
+func TestColumnField(t *testing.T) {
+	tests := map[string]schema.FieldType{
+		"BOOL":        schema.Boolean,
+		"INT4":        schema.Integer,
+		"INT8":        schema.Integer,
+		"FLOAT8":      schema.Float,
+		"NUMERIC":     schema.Float,
+		"DATE":        schema.Date,
+		"TIMESTAMPTZ": schema.Datetime,
+		"TIME":        schema.String,
+		"TEXT":        schema.String,
+		"BYTEA":       schema.String,
+		"JSONB":       schema.String,
+	}
+	for typ, want := range tests {
+		f := columnField("col", typ)
+		if f.Type != want {
+			t.Errorf("%s: got %s, want %s", typ, f.Type.Name, want.Name)
+		}
+		if f.Name != "col" || !f.Required || !f.Nullable {
+			t.Errorf("%s: got %+v", typ, f)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "PostgreSQL",
+	Version:     "0.1.0-alpha",
+	Description: "Filters, modifies and stores messages with PostgreSQL queries.",
+}
+
+// Config configures the satellite, e.g. with GOGARIN_SATELLITE_POSTGRES_MAXOPENCONNS=10.
+// The limits apply to the pool of each connection string, see Pools.
+type Config struct {
+	// MaxOpenConns limits the number of the open connections, 0 means unlimited.
+	MaxOpenConns int `default:"10"`
+
+	// MaxIdleConns limits the number of the idle connections kept open.
+	MaxIdleConns int `default:"2"`
+
+	// ConnMaxLifetimeInMs limits the time a connection is reused, 0 means forever.
+	ConnMaxLifetimeInMs int `default:"300000"`
+
+	// QueryTimeoutInMs limits the time of running the queries of a message,
+	// including waiting for a connection.
+	QueryTimeoutInMs int `default:"10000"`
+
+	// PoolIdleTimeoutInMs is how long the pool of a connection string is kept unused,
+	// e.g. after the missions connecting to the database have been removed.
+	// It is never less than QueryTimeoutInMs, so that no query is running on a closed pool.
+	PoolIdleTimeoutInMs int `default:"600000"`
+}
+
+// New returns the satellite with all its abilities connecting to the databases through the pools.
+func New(conn transport.Connection, pools *Pools, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+
+	sat.AddFilter(
+		satellite.Filter{
+			Call: pools.Exists,
+			Info: satellite.AbilityInfo{
+				Name:        "Exists",
+				Description: "Passes the messages, for which the query returns a row.",
+			},
+			Config:    QueryConfig{},
+			Validator: ValidateQuery,
+		},
+	)
+
+	sat.AddModifier(
+		satellite.Modifier{
+			Call: pools.Select,
+			Info: satellite.AbilityInfo{
+				Name:        "Select",
+				Description: "Adds the rows returned by the query to the messages.",
+			},
+			Config:     SelectConfig{},
+			Validator:  ValidateSelect,
+			OutputFunc: pools.DescribeSelect,
+		},
+	)
+
+	sat.AddAction(
+		satellite.Action{
+			Call: pools.Execute,
+			Info: satellite.AbilityInfo{
+				Name:        "Execute",
+				Description: "Runs INSERT, UPDATE or DELETE statements in a transaction.",
+			},
+			Config:    ExecuteConfig{},
+			Output:    ExecuteFields(),
+			Validator: ValidateExecute,
+		},
+	)
+
+	return sat
+}
@@ -0,0 +1,204 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/antonkuzmenko/gogarin/pkg/expr"
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	redigo "github.com/garyburd/redigo/redis"
+)
+
+// SetConfig is the key to set to the value, which is the message as JSON by default.
+// The key and the value are templates of the message fields, see satellite.Template.
+type SetConfig struct {
+	Key      string `json:"key" required:"true" desc:"Key, e.g. seen:{{issue.key}}."`
+	Value    string `json:"value" desc:"Value, e.g. {{issue.status}}. Defaults to the message as JSON."`
+	TTLInSec int    `json:"ttlInSec" min:"0" desc:"Expires the key after the number of seconds, 0 keeps it forever."`
+	IfAbsent bool   `json:"ifAbsent" desc:"Sets the key only if it does not exist."`
+}
+
+// HSetConfig is the field of the hash to set to the value, which is the message as JSON by default.
+// The key, the field and the value are templates of the message fields.
+type HSetConfig struct {
+	Key   string `json:"key" required:"true" desc:"Key of the hash, e.g. issues:{{project}}."`
+	Field string `json:"field" required:"true" desc:"Field of the hash, e.g. {{issue.key}}."`
+	Value string `json:"value" desc:"Value of the field. Defaults to the message as JSON."`
+}
+
+// IncrConfig is the counter to increment. The key is a template of the message fields.
+type IncrConfig struct {
+	Key string `json:"key" required:"true" desc:"Key of the counter, e.g. issues:{{project}}:count."`
+	By  int    `json:"by" default:"1" desc:"Increment, negative to decrement."`
+}
+
+// ExpireConfig is the key to expire. The key is a template of the message fields.
+type ExpireConfig struct {
+	Key      string `json:"key" required:"true" desc:"Key."`
+	TTLInSec int    `json:"ttlInSec" required:"true" min:"1" desc:"Expires the key after the number of seconds."`
+}
+
+// LPushConfig is the list to prepend the value to, which is the message as JSON by default.
+// The key and the value are templates of the message fields.
+type LPushConfig struct {
+	Key   string `json:"key" required:"true" desc:"Key of the list, e.g. queue:{{project}}."`
+	Value string `json:"value" desc:"Prepended value. Defaults to the message as JSON."`
+}
+
+// Set sets the key to the value. It returns whether the key has been set,
+// it has not if IfAbsent is true and the key exists.
+func (c commands) Set(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	conf := config.(SetConfig)
+	key, err := renderKey(conf.Key, m)
+	if err != nil {
+		return nil, err
+	}
+	value, err := content(conf.Value, m)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{key, value}
+	if conf.TTLInSec > 0 {
+		args = append(args, "EX", conf.TTLInSec)
+	}
+	if conf.IfAbsent {
+		args = append(args, "NX")
+	}
+	_, err = redigo.String(c.doArgs(ctx, "SET", args...))
+	if err == redigo.ErrNil {
+		return satellite.Message{"set": false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"set": true}, nil
+}
+
+// HSet sets the field of the hash to the value. It returns whether the field has been created.
+func (c commands) HSet(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	conf := config.(HSetConfig)
+	value, err := content(conf.Value, m)
+	if err != nil {
+		return nil, err
+	}
+	args, err := render(m, conf.Key, conf.Field)
+	if err != nil {
+		return nil, err
+	}
+	n, err := redigo.Int(c.doArgs(ctx, "HSET", append(args, value)...))
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"created": n > 0}, nil
+}
+
+// Incr increments the counter by By, or by 1 if By is 0. A missing counter is incremented from 0.
+// It returns the new value.
+func (c commands) Incr(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	conf := config.(IncrConfig)
+	key, err := renderKey(conf.Key, m)
+	if err != nil {
+		return nil, err
+	}
+	by := conf.By
+	if by == 0 {
+		by = 1
+	}
+	n, err := redigo.Int64(c.doArgs(ctx, "INCRBY", key, by))
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"value": float64(n)}, nil
+}
+
+// Expire sets the time to live of the key. It returns whether the key exists.
+func (c commands) Expire(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	conf := config.(ExpireConfig)
+	key, err := renderKey(conf.Key, m)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := redigo.Bool(c.doArgs(ctx, "EXPIRE", key, conf.TTLInSec))
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"set": ok}, nil
+}
+
+// LPush prepends the value to the list. It returns the length of the list.
+func (c commands) LPush(ctx context.Context, config interface{}, m satellite.Message) (satellite.Message, error) {
+	conf := config.(LPushConfig)
+	value, err := content(conf.Value, m)
+	if err != nil {
+		return nil, err
+	}
+	key, err := renderKey(conf.Key, m)
+	if err != nil {
+		return nil, err
+	}
+	n, err := redigo.Int64(c.doArgs(ctx, "LPUSH", key, value))
+	if err != nil {
+		return nil, err
+	}
+	return satellite.Message{"length": float64(n)}, nil
+}
+
+// content renders the template, an empty template renders the message as JSON.
+func content(t string, m satellite.Message) (string, error) {
+	if t == "" {
+		data, err := json.Marshal(m)
+		return string(data), err
+	}
+	return satellite.Template(t).Render(m)
+}
+
+// Validate checks the templates of the config of any ability.
+// If the incoming messages are known, the placeholders must refer to their fields.
+func Validate(ctx context.Context, config interface{}) error {
+	var templates map[string]string
+	switch c := config.(type) {
+	case GetConfig:
+		templates = map[string]string{"key": c.Key, "value": c.Value}
+	case HExistsConfig:
+		templates = map[string]string{"key": c.Key, "field": c.Field}
+	case HGetConfig:
+		templates = map[string]string{"key": c.Key, "field": c.Field, "value": c.Value}
+	case SetConfig:
+		templates = map[string]string{"key": c.Key, "value": c.Value}
+	case HSetConfig:
+		templates = map[string]string{"key": c.Key, "field": c.Field, "value": c.Value}
+	case IncrConfig:
+		templates = map[string]string{"key": c.Key}
+	case ExpireConfig:
+		templates = map[string]string{"key": c.Key}
+	case LPushConfig:
+		templates = map[string]string{"key": c.Key, "value": c.Value}
+	}
+
+	input, _ := ctx.Value(satellite.ContextKeyInput).(schema.Fields)
+	var errs schema.ValidationErrors
+	for key, t := range templates {
+		paths, err := satellite.Template(t).Fields()
+		if err != nil {
+			errs = append(errs, schema.ValidationError{Path: key, Message: err.Error()})
+			continue
+		}
+		if input == nil {
+			continue
+		}
+		for _, p := range paths {
+			if _, ok := expr.Lookup(input, p); !ok {
+				errs = append(errs, schema.ValidationError{Path: key, Message: "unknown field {{" + p + "}}"})
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	redigo "github.com/garyburd/redigo/redis"
+)
+
+// GetConfig is the key, the existence or the value of which filters the messages.
+// The key and the value are templates of the message fields, see satellite.Template.
+type GetConfig struct {
+	Key    string `json:"key" required:"true" desc:"Key, e.g. seen:{{issue.key}}."`
+	Value  string `json:"value" desc:"Value the key must have. Any value passes if it is empty."`
+	Invert bool   `json:"invert" desc:"Passes the messages that do not match instead, e.g. the ones not seen before."`
+}
+
+// HExistsConfig is the field of the hash, the existence of which filters the messages.
+// The key and the field are templates of the message fields.
+type HExistsConfig struct {
+	Key    string `json:"key" required:"true" desc:"Key of the hash, e.g. issues:{{project}}."`
+	Field  string `json:"field" required:"true" desc:"Field of the hash, e.g. {{issue.key}}."`
+	Invert bool   `json:"invert" desc:"Passes the messages, for which the field does not exist, instead."`
+}
+
+// HGetConfig is the field of the hash, the existence or the value of which filters the messages.
+// The key, the field and the value are templates of the message fields.
+type HGetConfig struct {
+	Key    string `json:"key" required:"true" desc:"Key of the hash."`
+	Field  string `json:"field" required:"true" desc:"Field of the hash."`
+	Value  string `json:"value" desc:"Value the field must have. Any value passes if it is empty."`
+	Invert bool   `json:"invert" desc:"Passes the messages that do not match instead."`
+}
+
+// commands run the commands of the abilities with the connections of the pool.
+type commands struct {
+	pool *redigo.Pool
+}
+
+// do renders the templates of the keys or the fields and runs the command with them.
+func (c commands) do(ctx context.Context, m satellite.Message, cmd string, templates ...string) (interface{}, error) {
+	args, err := render(m, templates...)
+	if err != nil {
+		return nil, err
+	}
+	return c.doArgs(ctx, cmd, args...)
+}
+
+// render renders the templates of the keys or the fields, see renderKey.
+func render(m satellite.Message, templates ...string) ([]interface{}, error) {
+	args := make([]interface{}, len(templates))
+	for i, t := range templates {
+		s, err := renderKey(t, m)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = s
+	}
+	return args, nil
+}
+
+// renderKey renders the template of a key or a field. Unlike a value, it fails if a placeholder
+// is absent or null, e.g. seen:{{issue.key}} would be the same seen: key of all the messages without it.
+func renderKey(t string, m satellite.Message) (string, error) {
+	paths, err := satellite.Template(t).Fields()
+	if err != nil {
+		return "", err
+	}
+	for _, p := range paths {
+		if v, _ := m.Get(p); v == nil {
+			return "", fmt.Errorf("field {{%s}} of %s is absent or null", p, t)
+		}
+	}
+	return satellite.Template(t).Render(m)
+}
+
+func (c commands) doArgs(ctx context.Context, cmd string, args ...interface{}) (interface{}, error) {
+	con, err := c.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer con.Close()
+	return con.Do(cmd, args...)
+}
+
+// Get passes the messages, for which the key exists and has the value, if any.
+func (c commands) Get(ctx context.Context, config interface{}, m satellite.Message) (bool, error) {
+	conf := config.(GetConfig)
+	v, err := redigo.String(c.do(ctx, m, "GET", conf.Key))
+	return c.match(v, err, conf.Value, m, conf.Invert)
+}
+
+// HExists passes the messages, for which the field of the hash exists.
+func (c commands) HExists(ctx context.Context, config interface{}, m satellite.Message) (bool, error) {
+	conf := config.(HExistsConfig)
+	ok, err := redigo.Bool(c.do(ctx, m, "HEXISTS", conf.Key, conf.Field))
+	if err != nil {
+		return false, err
+	}
+	return ok != conf.Invert, nil
+}
+
+// HGet passes the messages, for which the field of the hash exists and has the value, if any.
+func (c commands) HGet(ctx context.Context, config interface{}, m satellite.Message) (bool, error) {
+	conf := config.(HGetConfig)
+	v, err := redigo.String(c.do(ctx, m, "HGET", conf.Key, conf.Field))
+	return c.match(v, err, conf.Value, m, conf.Invert)
+}
+
+// match reports whether the reply of GET or HGET matches the value template.
+func (c commands) match(v string, err error, value string, m satellite.Message, invert bool) (bool, error) {
+	if err == redigo.ErrNil {
+		return invert, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if value == "" {
+		return !invert, nil
+	}
+	want, err := satellite.Template(value).Render(m)
+	if err != nil {
+		return false, err
+	}
+	return (v == want) != invert, nil
+}
@@ -0,0 +1,23 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+)
+
+func TestRenderKey(t *testing.T) {
+	m := satellite.Message{
+		"issue": map[string]interface{}{"key": "A-1", "points": 3.0, "assignee": nil},
+	}
+
+	key, err := renderKey("seen:{{issue.key}}:{{issue.points}}", m)
+	if err != nil || key != "seen:A-1:3" {
+		t.Errorf("got %q, %v", key, err)
+	}
+	for _, tmpl := range []string{"seen:{{issue.summary}}", "by:{{issue.assignee}}", "seen:{{issue.key"} {
+		if key, err := renderKey(tmpl, m); err == nil {
+			t.Errorf("%s: got %q, want an error", tmpl, key)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package redis
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	redigo "github.com/garyburd/redigo/redis"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "Redis",
+	Version:     "0.1.0-alpha",
+	Description: "Filters messages by redis keys and stores them in redis, e.g. to skip duplicates or count them.",
+}
+
+// New returns the satellite with all its abilities running the commands with the connections of the pool,
+// see the redis transport's NewPool.
+func New(conn transport.Connection, pool *redigo.Pool, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+	c := commands{pool: pool}
+
+	filters := []satellite.Filter{
+		{
+			Call: c.Get,
+			Info: satellite.AbilityInfo{
+				Name:        "Get",
+				Description: "Passes the messages, for which the key exists and has the value, if any.",
+			},
+			Config: GetConfig{},
+		},
+		{
+			Call: c.HExists,
+			Info: satellite.AbilityInfo{
+				Name:        "HExists",
+				Description: "Passes the messages, for which the field of the hash exists.",
+			},
+			Config: HExistsConfig{},
+		},
+		{
+			Call: c.HGet,
+			Info: satellite.AbilityInfo{
+				Name:        "HGet",
+				Description: "Passes the messages, for which the field of the hash exists and has the value, if any.",
+			},
+			Config: HGetConfig{},
+		},
+	}
+	for _, f := range filters {
+		f.Validator = Validate
+		sat.AddFilter(f)
+	}
+
+	actions := []satellite.Action{
+		{
+			Call:   c.Set,
+			Info:   satellite.AbilityInfo{Name: "Set", Description: "Sets the key to the value."},
+			Config: SetConfig{},
+			Output: boolFields("set", "Set", "Whether the key has been set."),
+		},
+		{
+			Call:   c.HSet,
+			Info:   satellite.AbilityInfo{Name: "HSet", Description: "Sets the field of the hash to the value."},
+			Config: HSetConfig{},
+			Output: boolFields("created", "Created", "Whether the field has been created."),
+		},
+		{
+			Call:   c.Incr,
+			Info:   satellite.AbilityInfo{Name: "Incr", Description: "Increments the counter."},
+			Config: IncrConfig{},
+			Output: intFields("value", "Value", "Value of the counter."),
+		},
+		{
+			Call:   c.Expire,
+			Info:   satellite.AbilityInfo{Name: "Expire", Description: "Sets the time to live of the key."},
+			Config: ExpireConfig{},
+			Output: boolFields("set", "Set", "Whether the key exists."),
+		},
+		{
+			Call:   c.LPush,
+			Info:   satellite.AbilityInfo{Name: "LPush", Description: "Prepends the value to the list."},
+			Config: LPushConfig{},
+			Output: intFields("length", "Length", "Length of the list."),
+		},
+	}
+	for _, a := range actions {
+		a.Validator = Validate
+		sat.AddAction(a)
+	}
+
+	return sat
+}
+
+func boolFields(key, name, description string) schema.Fields {
+	return schema.Fields{key: {Name: name, Type: schema.Boolean, Description: description, Required: true}}
+}
+
+func intFields(key, name, description string) schema.Fields {
+	return schema.Fields{key: {Name: name, Type: schema.Integer, Description: description, Required: true}}
+}
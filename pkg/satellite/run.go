@@ -0,0 +1,68 @@
+package satellite
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	jsonLogger   = "json"
+	logfmtLogger = "logfmt"
+)
+
+// NewLogger returns the logger of the format of the config, json or logfmt, writing to stderr.
+// The entries have the timestamp and the keyvals, e.g. the version of the binary.
+// NewLogger panics if the format is unknown.
+func NewLogger(c Config, keyvals ...interface{}) log.Logger {
+	var logger log.Logger
+
+	switch c.Logger {
+	case jsonLogger:
+		logger = log.NewJSONLogger(log.NewSyncWriter(os.Stderr))
+	case logfmtLogger:
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	default:
+		panic("invalid logger format: " + c.Logger)
+	}
+
+	return log.With(logger, append([]interface{}{"ts", log.DefaultTimestampUTC}, keyvals...)...)
+}
+
+// Run starts the satellite and blocks until the process receives SIGINT or SIGTERM.
+// Then it calls the shutdown funcs, e.g. to stop the servers feeding the triggers, and stops
+// the satellite, all within Transport.ShutdownTimeoutInMs. Run panics if the satellite cannot be started.
+func Run(s *Satellite, c Config, logger log.Logger, shutdown ...func(ctx context.Context) error) {
+	err := s.Start(c)
+	if err != nil {
+		panic(err)
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigs
+	level.Info(logger).Log("sig", sig)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		time.Duration(c.Transport.ShutdownTimeoutInMs)*time.Millisecond,
+	)
+	defer cancel()
+
+	for _, f := range shutdown {
+		err = f(ctx)
+		if err != nil {
+			level.Error(logger).Log("err", err)
+		}
+	}
+
+	err = s.Stop(ctx)
+	if err != nil {
+		level.Error(logger).Log("err", err)
+	}
+}
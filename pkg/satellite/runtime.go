@@ -0,0 +1,298 @@
+package satellite
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/antonkuzmenko/gogarin/pkg/transport/redis"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/oklog/ulid"
+)
+
+var (
+	// ErrTriggerRunning is returned when a trigger with the same ID is already running.
+	ErrTriggerRunning = errors.New("trigger is already running")
+
+	// ErrTriggerNotRunning is returned when there is no running trigger with the ID.
+	ErrTriggerNotRunning = errors.New("trigger is not running")
+)
+
+type contextKey int
+
+const (
+	// ContextKeyTriggerID is populated in the context passed to a TriggerFunc.
+	// Its value is the ID of the StartTriggerRequest. It does not change when the trigger
+	// is restarted, so it is useful to persist the progress of the trigger.
+	ContextKeyTriggerID contextKey = iota
+
+	// ContextKeyNamespace is populated in the context passed to a TriggerFunc.
+	// Its value is the namespace of the satellite, see TransportConfig.Namespace.
+	ContextKeyNamespace
+
+	// ContextKeyInput is populated in the context passed to a ValidatorFunc, if the space center
+	// knows the schema of the messages the ability receives in the mission, e.g. the Output
+	// of the trigger preceding a filter. Its value is schema.Fields.
+	ContextKeyInput
+
+	// ContextKeyTopic is populated in the context passed to a TriggerFunc.
+	// Its value is the topic the trigger emits to, see StartTriggerRequest.
+	ContextKeyTopic
+)
+
+// AbilityTopic returns the topic the satellite receives the requests for the ability from.
+// Trigger topics receive StartTriggerRequest, the others receive DispatchRequest.
+func AbilityTopic(satellite string, kind Kind, ability string) string {
+	return strings.Join(
+		[]string{"satellite", slug(satellite), string(kind), slug(ability)},
+		transport.TokenSeparator,
+	)
+}
+
+// ValidateConfigTopic returns the topic the satellite receives ValidateConfigRequest for the ability from.
+func ValidateConfigTopic(satellite string, kind Kind, ability string) string {
+	return AbilityTopic(satellite, kind, ability) + transport.TokenSeparator + "validate"
+}
+
+// InstanceTopic returns the topic a satellite instance receives StopTriggerRequest from.
+func InstanceTopic(satellite, instance string) string {
+	return strings.Join(
+		[]string{"satellite", slug(satellite), "instance", instance, "stop"},
+		transport.TokenSeparator,
+	)
+}
+
+// slug turns a name, e.g. "File System Events", into a topic token, e.g. "file-system-events".
+func slug(name string) string {
+	var b bytes.Buffer
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+func newInstanceID() string {
+	t := time.Now()
+	entropy := rand.New(rand.NewSource(t.UnixNano()))
+	return ulid.MustNew(ulid.Timestamp(t), entropy).String()
+}
+
+// serve starts a transport.Server receiving the requests for the abilities.
+func (s *Satellite) serve(c Config) {
+	logger := log.With(s.logger, "component", "transport.Server")
+	s.server = transport.NewServer(
+		s.conn,
+		time.Duration(c.Transport.PollTimeoutInMs)*time.Millisecond,
+		logger,
+		transport.ServerNamespace(c.Transport.Namespace),
+	)
+
+	options := []redis.ServerOption{
+		redis.ServerLogger(log.With(s.logger, "component", "redis.Server")),
+	}
+	validateConfig := func(kind Kind, name string) {
+		s.server.Handle(
+			ValidateConfigTopic(s.Info.Name, kind, name),
+			redis.NewServer(
+				makeValidateConfigEndpoint(s, kind, name),
+				decodeJSONValidateConfigRequest,
+				encodeJSONResponse,
+				options...,
+			),
+		)
+	}
+	dispatch := func(kind Kind, name string) {
+		s.server.Handle(
+			AbilityTopic(s.Info.Name, kind, name),
+			redis.NewServer(
+				makeDispatchEndpoint(s, kind, name),
+				decodeJSONDispatchRequest,
+				encodeJSONResponse,
+				options...,
+			),
+		)
+		validateConfig(kind, name)
+	}
+
+	for _, t := range s.Triggers {
+		validateConfig(KindTrigger, t.Info.Name)
+		s.server.Handle(
+			AbilityTopic(s.Info.Name, KindTrigger, t.Info.Name),
+			redis.NewServer(
+				makeStartTriggerEndpoint(s, t),
+				decodeJSONStartTriggerRequest,
+				encodeJSONResponse,
+				options...,
+			),
+		)
+	}
+	if len(s.Triggers) > 0 {
+		s.server.Handle(
+			InstanceTopic(s.Info.Name, s.instance),
+			redis.NewServer(
+				makeStopTriggerEndpoint(s),
+				decodeJSONStopTriggerRequest,
+				encodeJSONResponse,
+				options...,
+			),
+		)
+	}
+	for _, f := range s.Filters {
+		dispatch(KindFilter, f.Info.Name)
+	}
+	for _, m := range s.Modifiers {
+		dispatch(KindModifier, m.Info.Name)
+	}
+	for _, a := range s.Actions {
+		dispatch(KindAction, a.Info.Name)
+	}
+	for _, sp := range s.Splitters {
+		dispatch(KindSplitter, sp.Info.Name)
+	}
+
+	go func() {
+		err := s.server.Serve()
+		if err != transport.ErrServerClosed {
+			level.Error(logger).Log("err", err, "context", "Serve")
+		}
+	}()
+}
+
+// reregister periodically registers the satellite, so that the space center
+// learns about it again after a restart.
+func (s *Satellite) reregister(ctx context.Context, c Config) {
+	interval := time.Duration(c.Transport.ReregisterIntervalInSec) * time.Second
+	b := transport.NewBackoff(time.Second, interval)
+	wait := interval
+
+	for {
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+
+		err := s.register(ctx, c)
+		if err != nil {
+			level.Error(s.logger).Log("err", err, "context", "register", "attempt", b.Attempt()+1)
+			wait = b.Next()
+			continue
+		}
+		b.Reset()
+		wait = interval
+	}
+}
+
+func (s *Satellite) register(ctx context.Context, c Config) error {
+	registerEndpoint := makeRegisterEndpoint(c, s.conn)
+	_, err := registerEndpoint(ctx, s.Registration())
+	return err
+}
+
+func (s *Satellite) startTrigger(ctx context.Context, t Trigger, req StartTriggerRequest) error {
+	a := abilityConfig{prototype: t.Config, fields: t.ConfigFields, validator: t.Validator}
+	config, err := a.decode(ctx, req.Config)
+	if errs, ok := err.(schema.ValidationErrors); ok {
+		return errs.Prefix("config")
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.triggers[req.ID]; ok {
+		return ErrTriggerRunning
+	}
+
+	ctx = context.WithValue(s.ctx, ContextKeyTriggerID, req.ID)
+	ctx = context.WithValue(ctx, ContextKeyNamespace, s.namespace)
+	ctx = context.WithValue(ctx, ContextKeyTopic, req.Topic)
+	ctx, cancel := context.WithCancel(ctx)
+	s.triggers[req.ID] = cancel
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+		defer func() {
+			s.mu.Lock()
+			delete(s.triggers, req.ID)
+			s.mu.Unlock()
+			cancel()
+		}()
+
+		level.Info(s.logger).Log("trigger", t.Info.Name, "id", req.ID, "status", "started")
+		s.runTrigger(ctx, t, config, req)
+		level.Info(s.logger).Log("trigger", t.Info.Name, "id", req.ID, "status", "stopped")
+	}()
+
+	return nil
+}
+
+// runTrigger calls the trigger until it is stopped. A failed trigger is restarted with a backoff,
+// which starts over if the trigger has run longer than the max delay.
+func (s *Satellite) runTrigger(ctx context.Context, t Trigger, config interface{}, req StartTriggerRequest) {
+	b := transport.NewBackoff(s.triggerBackoff, s.maxTriggerBackoff)
+	for {
+		started := time.Now()
+		err := t.Call(ctx, config, s.Emitter(req.Topic))
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > s.maxTriggerBackoff {
+			b.Reset()
+		}
+
+		level.Error(s.logger).Log("err", err, "trigger", t.Info.Name, "id", req.ID, "attempt", b.Attempt()+1)
+		if b.Wait(ctx) != nil {
+			return
+		}
+		level.Info(s.logger).Log("trigger", t.Info.Name, "id", req.ID, "status", "restarted")
+	}
+}
+
+func (s *Satellite) stopTrigger(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancel, ok := s.triggers[id]
+	if !ok {
+		return ErrTriggerNotRunning
+	}
+	cancel()
+	return nil
+}
+
+// Emitter returns an Emitter that publishes JSON encoded messages to the topic,
+// e.g. for a trigger emitting on behalf of the one started on another instance.
+// It must be called after Start.
+func (s *Satellite) Emitter(topic string) Emitter {
+	topic = transport.NamespacedTopic(s.namespace, topic)
+	return EmitterFunc(func(ctx context.Context, m Message) error {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		return s.conn.Send(topic, transport.NoReply, data)
+	})
+}
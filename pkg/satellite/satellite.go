@@ -1,45 +1,193 @@
+// Package satellite runs the abilities of a satellite: triggers, filters, modifiers, splitters and actions,
+// which are combined into the steps of missions.
+//
+// Every ability has the same shape. Config is a zero value of the ability's config type, it is used
+// to decode the config of a mission step before the config is passed to Call and Validator.
+// ConfigFields describes the config, Input and Output describe the incoming and outgoing messages
+// (triggers only emit), OutputFunc overrides Output for the config, if any.
 package satellite
 
 import (
 	"context"
+	"sync"
+	"time"
 
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
 	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/go-kit/kit/log"
 )
 
 type Config struct {
 	Transport TransportConfig
 	Logger    string `default:"json"`
+
+	// TriggerBackoffInMs is a delay before a failed trigger is restarted. The delay doubles
+	// on each subsequent failure until it reaches MaxTriggerBackoffInMs.
+	// The default TriggerBackoffInMs is 1000ms/1s.
+	TriggerBackoffInMs int `default:"1000"`
+
+	// MaxTriggerBackoffInMs is the upper limit of a delay between trigger restarts.
+	// A trigger that fails after running longer than that is restarted after TriggerBackoffInMs again.
+	// The default MaxTriggerBackoffInMs is 60000ms/60s.
+	MaxTriggerBackoffInMs int `default:"60000"`
 }
 
-func New(r transport.Connection, i Info) *Satellite {
-	return &Satellite{
-		conn: r,
-		Info: i,
+func New(r transport.Connection, i Info, options ...Option) *Satellite {
+	s := &Satellite{
+		conn:     r,
+		Info:     i,
+		logger:   log.NewNopLogger(),
+		instance: newInstanceID(),
+		triggers: make(map[string]context.CancelFunc),
+	}
+	for _, option := range options {
+		option(s)
 	}
+	return s
+}
+
+// Option sets an optional parameter for satellites.
+type Option func(*Satellite)
+
+// Logger sets the logger used by the satellite runtime. By default, nothing is logged.
+func Logger(l log.Logger) Option {
+	return func(s *Satellite) { s.logger = l }
 }
 
 type Satellite struct {
-	conn     transport.Connection
-	Info     Info
-	Triggers []Trigger
-	Actions  []Action
+	conn      transport.Connection
+	logger    log.Logger
+	instance  string
+	namespace string
+	server    *transport.Server
+
+	triggerBackoff    time.Duration
+	maxTriggerBackoff time.Duration
+
+	// ctx is canceled by Stop, it stops the running triggers and re-registration.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	triggers   map[string]context.CancelFunc
+	background []func(ctx context.Context)
+	running    sync.WaitGroup
+
+	Info      Info
+	Triggers  []Trigger
+	Filters   []Filter
+	Modifiers []Modifier
+	Actions   []Action
+	Splitters []Splitter
 }
 
+// Logger returns the logger of the satellite, e.g. for the abilities that log their errors and carry on.
+func (s *Satellite) Logger() log.Logger {
+	return s.logger
+}
+
+// AddTrigger registers the trigger, it is started by a start request and runs until it is stopped.
+// Missing ConfigFields are derived from the Config struct, see schema.FieldsOf, AddTrigger panics if they cannot be.
 func (s *Satellite) AddTrigger(t Trigger) {
+	if t.ConfigFields == nil {
+		t.ConfigFields = schema.MustFieldsOf(t.Config)
+	}
 	s.Triggers = append(s.Triggers, t)
 }
 
+// AddFilter registers the filter, a dispatched message is dropped unless the filter passes it.
+// AddFilter panics if ConfigFields is nil and the Config is not a struct schema.FieldsOf can describe.
+func (s *Satellite) AddFilter(f Filter) {
+	if f.ConfigFields == nil {
+		f.ConfigFields = schema.MustFieldsOf(f.Config)
+	}
+	s.Filters = append(s.Filters, f)
+}
+
+// AddModifier registers the modifier, a dispatched message is replaced with the one it returns.
+// Like the other Add methods, it fills in missing ConfigFields from the Config struct.
+func (s *Satellite) AddModifier(m Modifier) {
+	if m.ConfigFields == nil {
+		m.ConfigFields = schema.MustFieldsOf(m.Config)
+	}
+	s.Modifiers = append(s.Modifiers, m)
+}
+
+// AddAction registers the action, the message it returns is the result of the dispatched one.
+// Like the other Add methods, it fills in missing ConfigFields from the Config struct.
 func (s *Satellite) AddAction(a Action) {
+	if a.ConfigFields == nil {
+		a.ConfigFields = schema.MustFieldsOf(a.Config)
+	}
 	s.Actions = append(s.Actions, a)
 }
 
+// AddSplitter registers the splitter, each of the messages it returns goes on to the next step.
+// Like the other Add methods, it fills in missing ConfigFields from the Config struct.
+func (s *Satellite) AddSplitter(sp Splitter) {
+	if sp.ConfigFields == nil {
+		sp.ConfigFields = schema.MustFieldsOf(sp.Config)
+	}
+	s.Splitters = append(s.Splitters, sp)
+}
+
+// Start registers the satellite with the space center and starts serving its abilities.
+// Afterwards, the satellite re-registers periodically, so that a restarted space center
+// knows about it. Start does not block, call Stop to stop the satellite.
 func (s *Satellite) Start(c Config) error {
-	registerEndpoint := makeRegisterEndpoint(c, s.conn)
-	_, err := registerEndpoint(context.TODO(), s.Info)
-	return err
+	err := s.register(context.Background(), c)
+	if err != nil {
+		return err
+	}
+
+	s.namespace = c.Transport.Namespace
+	s.triggerBackoff = time.Duration(c.TriggerBackoffInMs) * time.Millisecond
+	s.maxTriggerBackoff = time.Duration(c.MaxTriggerBackoffInMs) * time.Millisecond
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.serve(c)
+	go s.reregister(s.ctx, c)
+
+	ctx := context.WithValue(s.ctx, ContextKeyNamespace, s.namespace)
+	for _, f := range s.background {
+		s.running.Add(1)
+		go func(f func(ctx context.Context)) {
+			defer s.running.Done()
+			f(ctx)
+		}(f)
+	}
+	return nil
 }
 
-func (s *Satellite) Stop() {
+// Go runs f in the background from Start until Stop, e.g. a task the instances of a trigger share.
+// The context has ContextKeyNamespace, it is canceled by Stop, which waits for f to return.
+// Go must be called before Start.
+func (s *Satellite) Go(f func(ctx context.Context)) {
+	s.background = append(s.background, f)
+}
+
+// Stop gracefully stops the satellite. It stops receiving new requests, waits for
+// the in-flight requests to complete and stops the running triggers and the background tasks.
+// If the provided context expires before the satellite stops, Stop returns the context's error.
+func (s *Satellite) Stop(ctx context.Context) error {
+	if s.server == nil {
+		return nil
+	}
+
+	err := s.server.Shutdown(ctx)
+	s.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+	}
+	return err
 }
 
 type Info struct {
@@ -48,18 +196,63 @@ type Info struct {
 	Description string
 }
 
+// Trigger produces messages, e.g. when a file is created.
 type Trigger struct {
-	Call      func()
-	Info      AbilityInfo
-	Config    interface{}
-	Validator func(config interface{})
+	Call         TriggerFunc
+	Info         AbilityInfo
+	Config       interface{}
+	ConfigFields schema.Fields
+	Output       schema.Fields
+	Validator    ValidatorFunc
+	OutputFunc   OutputFunc
+}
+
+// Filter passes or drops an incoming message, e.g. when a field has a certain value.
+type Filter struct {
+	Call         FilterFunc
+	Info         AbilityInfo
+	Config       interface{}
+	ConfigFields schema.Fields
+	Input        schema.Fields
+	Output       schema.Fields
+	Validator    ValidatorFunc
+	OutputFunc   OutputFunc
+}
+
+// Modifier changes an incoming message, e.g. adds or removes its fields.
+type Modifier struct {
+	Call         ModifierFunc
+	Info         AbilityInfo
+	Config       interface{}
+	ConfigFields schema.Fields
+	Input        schema.Fields
+	Output       schema.Fields
+	Validator    ValidatorFunc
+	OutputFunc   OutputFunc
+}
+
+// Splitter turns an incoming message into many, e.g. one per item of a collection.
+type Splitter struct {
+	Call         SplitterFunc
+	Info         AbilityInfo
+	Config       interface{}
+	ConfigFields schema.Fields
+	Input        schema.Fields
+	Output       schema.Fields
+	Validator    ValidatorFunc
+	OutputFunc   OutputFunc
 }
 
+// Action does something with an incoming message, e.g. appends it to a file.
 type Action struct {
-	Call      func()
-	Info      AbilityInfo
-	Config    interface{}
-	Validator func(config interface{})
+	Call         ActionFunc
+	Info         AbilityInfo
+	Config       interface{}
+	ConfigFields schema.Fields
+	Input        schema.Fields
+	Output       schema.Fields
+	Validator    ValidatorFunc
+	OutputFunc   OutputFunc
 }
 
 type AbilityInfo struct {
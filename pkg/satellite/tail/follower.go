@@ -0,0 +1,301 @@
+package tail
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"regexp"
+	"time"
+)
+
+const (
+	// maxLineSize limits the size of a line, longer lines are split.
+	maxLineSize = 1 << 20
+	// saveInterval limits how often the position is saved while the file grows.
+	saveInterval = time.Second
+)
+
+// record is a line or, if the lines are joined, a group of lines.
+// Offset is the offset of its first line.
+type record struct {
+	offset int64
+	lines  []string
+}
+
+// follower reads the lines appended to a file. It reopens the file when it is rotated
+// and reads it from the beginning when it is truncated.
+type follower struct {
+	path       string
+	fromStart  bool
+	multiline  *regexp.Regexp
+	maxLines   int
+	poll       time.Duration
+	flushAfter time.Duration
+	state      *state
+
+	file   *os.File
+	info   os.FileInfo
+	reader *bufio.Reader
+	// offset is the offset after the last complete line.
+	offset  int64
+	partial []byte
+	pending *record
+	saved   int64
+	// created is true if the file did not exist, when it was first opened.
+	created bool
+}
+
+// run calls emit for every record until the context is canceled or emit returns an error.
+func (f *follower) run(ctx context.Context, emit func(record) error) error {
+	ticker := time.NewTicker(f.poll)
+	defer ticker.Stop()
+	defer func() {
+		if f.file != nil {
+			f.file.Close()
+		}
+	}()
+
+	var (
+		idle     time.Duration
+		lastSave = time.Now()
+	)
+	for {
+		var read bool
+		var err error
+		if f.file == nil {
+			err = f.open()
+		} else {
+			read, err = f.read(emit)
+		}
+		if err != nil {
+			return err
+		}
+
+		if !read && f.file != nil {
+			idle += f.poll
+			if f.pending != nil && idle >= f.flushAfter {
+				err = f.flush(emit)
+			}
+			if err == nil {
+				err = f.checkRotation(emit)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if read {
+			idle = 0
+		}
+
+		if f.file != nil && (!read || time.Since(lastSave) >= saveInterval) {
+			err = f.save()
+			if err != nil {
+				return err
+			}
+			lastSave = time.Now()
+		}
+
+		if read {
+			select {
+			case <-ctx.Done():
+				return f.save()
+			default:
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return f.save()
+		case <-ticker.C:
+		}
+	}
+}
+
+// open opens the file at the saved position. Without a saved position, the file is read
+// from its end, unless fromStart is true or the file has been created after the trigger started.
+// If the file does not exist yet, open does nothing.
+func (f *follower) open() error {
+	file, err := os.Open(f.path)
+	if os.IsNotExist(err) {
+		f.created = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	var offset int64
+	pos, ok := f.state.get(f.path)
+	switch {
+	case ok && pos.Offset <= info.Size() && pos.matches(file):
+		offset = pos.Offset
+	case ok:
+		// Rotated while the trigger was stopped.
+		offset = 0
+	case !f.fromStart && !f.created:
+		offset = info.Size()
+	}
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file, f.info, f.reader = file, info, bufio.NewReader(file)
+	f.offset, f.saved, f.partial = offset, -1, nil
+	return nil
+}
+
+// read reads the complete lines available in the file and reports whether anything has been read.
+// A line is read in chunks of the reader's buffer, so that a line longer than maxLineSize
+// is split without being read into memory as a whole.
+func (f *follower) read(emit func(record) error) (bool, error) {
+	read := false
+	for {
+		b, err := f.reader.ReadSlice('\n')
+		if len(b) > 0 {
+			read = true
+		}
+
+		for {
+			n := maxLineSize - len(f.partial)
+			if err == nil && len(b) == n+1 {
+				// The newline does not count.
+				break
+			}
+			if len(b) <= n {
+				break
+			}
+			f.partial = append(f.partial, b[:n]...)
+			b = b[n:]
+			lerr := f.line(emit)
+			if lerr != nil {
+				return read, lerr
+			}
+		}
+		f.partial = append(f.partial, b...)
+
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF:
+			return read, nil
+		case err != nil:
+			return read, err
+		}
+
+		err = f.line(emit)
+		if err != nil {
+			return read, err
+		}
+	}
+}
+
+// line passes the complete line read to the current record.
+func (f *follower) line(emit func(record) error) error {
+	offset := f.offset
+	text := string(bytes.TrimRight(f.partial, "\r\n"))
+	f.offset += int64(len(f.partial))
+	f.partial = nil
+
+	if f.multiline == nil {
+		return emit(record{offset: offset, lines: []string{text}})
+	}
+
+	if f.pending != nil && !f.multiline.MatchString(text) && len(f.pending.lines) < f.maxLines {
+		f.pending.lines = append(f.pending.lines, text)
+		return nil
+	}
+	err := f.flush(emit)
+	f.pending = &record{offset: offset, lines: []string{text}}
+	return err
+}
+
+// flush emits the record being joined, if any.
+func (f *follower) flush(emit func(record) error) error {
+	if f.pending == nil {
+		return nil
+	}
+	r := *f.pending
+	f.pending = nil
+	return emit(r)
+}
+
+// checkRotation reopens the file if it has been rotated
+// and reads it from the beginning if it has been truncated.
+func (f *follower) checkRotation(emit func(record) error) error {
+	info, err := os.Stat(f.path)
+	if os.IsNotExist(err) {
+		// Rotated, but not created yet.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case !os.SameFile(info, f.info):
+		// The writer might have appended to the rotated file before it reopened the new one.
+		_, err = f.read(emit)
+		if err == nil && len(f.partial) > 0 {
+			err = f.line(emit)
+		}
+		if err == nil {
+			err = f.flush(emit)
+		}
+		if err != nil {
+			return err
+		}
+
+		f.file.Close()
+		f.file = nil
+		f.created = true
+		f.state.remove(f.path)
+		return f.open()
+
+	case info.Size() < f.offset+int64(len(f.partial)):
+		err = f.flush(emit)
+		if err != nil {
+			return err
+		}
+		_, err = f.file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		f.reader.Reset(f.file)
+		f.info = info
+		f.offset, f.partial = 0, nil
+	}
+	return nil
+}
+
+// save saves the offset of the first line that has not been emitted yet.
+func (f *follower) save() error {
+	offset := f.offset
+	if f.pending != nil {
+		offset = f.pending.offset
+	}
+	if offset == f.saved {
+		return nil
+	}
+
+	fp, n, err := fingerprint(f.file, fingerprintSize)
+	if err != nil {
+		return err
+	}
+	err = f.state.set(f.path, position{Offset: offset, Fingerprint: fp, FingerprintSize: n})
+	if err != nil {
+		return err
+	}
+	f.saved = offset
+	return nil
+}
@@ -0,0 +1,51 @@
+package tail
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLongLines(t *testing.T) {
+	long := strings.Repeat("a", maxLineSize)
+	n := int64(maxLineSize)
+	tests := []struct {
+		text    string
+		lines   []string
+		offsets []int64
+	}{
+		{text: "one\r\ntwo\nthree", lines: []string{"one", "two"}, offsets: []int64{0, 5}},
+		{text: long + "\nb\n", lines: []string{long, "b"}, offsets: []int64{0, n + 1}},
+		{text: long + "bc\nd\n", lines: []string{long, "bc", "d"}, offsets: []int64{0, n, n + 3}},
+		{text: long + long + "e", lines: []string{long, long}, offsets: []int64{0, n}},
+	}
+	for _, tt := range tests {
+		f := &follower{reader: bufio.NewReader(strings.NewReader(tt.text))}
+		var lines []string
+		var offsets []int64
+		read, err := f.read(func(r record) error {
+			lines = append(lines, r.lines...)
+			offsets = append(offsets, r.offset)
+			return nil
+		})
+		if err != nil || !read {
+			t.Fatalf("got %v, %v", read, err)
+		}
+		if len(lines) != len(tt.lines) {
+			t.Errorf("got %d lines, want %d", len(lines), len(tt.lines))
+			continue
+		}
+		for i := range lines {
+			if lines[i] != tt.lines[i] {
+				t.Errorf("line %d: got %.20q of %d bytes, want %.20q", i, lines[i], len(lines[i]), tt.lines[i])
+			}
+		}
+		if !reflect.DeepEqual(offsets, tt.offsets) {
+			t.Errorf("got offsets %v, want %v", offsets, tt.offsets)
+		}
+		if len(f.partial) > maxLineSize {
+			t.Errorf("got a partial line of %d bytes", len(f.partial))
+		}
+	}
+}
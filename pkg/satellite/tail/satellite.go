@@ -0,0 +1,41 @@
+package tail
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "Tail",
+	Version:     "0.1.0-alpha",
+	Description: "Follows the lines appended to files.",
+}
+
+// Config configures the satellite, e.g. with GOGARIN_SATELLITE_TAIL_STATEDIR=/var/lib/gogarin/tail.
+type Config struct {
+	// StateDir is the directory, in which the positions in the followed files are saved.
+	// Without it, the restarted triggers start over as configured.
+	StateDir string
+}
+
+// New returns the satellite with all its abilities.
+func New(conn transport.Connection, c Config, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+	t := Tailer{stateDir: c.StateDir}
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: t.Tail,
+			Info: satellite.AbilityInfo{
+				Name:        "New Line",
+				Description: "Triggers when a line is appended to a file, following the file across rotation.",
+			},
+			Config:    TailConfig{},
+			Output:    TailFields(),
+			Validator: ValidateTail,
+		},
+	)
+
+	return sat
+}
@@ -0,0 +1,119 @@
+package tail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fingerprintSize is the number of the first bytes of a file that identify it.
+// A rotated file is recognized by its different beginning.
+const fingerprintSize = 512
+
+// position is the saved position in a file.
+type position struct {
+	Offset          int64
+	Fingerprint     string
+	FingerprintSize int
+}
+
+// fingerprint hashes the first bytes of the file.
+func fingerprint(f io.ReaderAt, size int) (string, int, error) {
+	buf := make([]byte, size)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(buf[:n])
+	return hex.EncodeToString(sum[:]), n, nil
+}
+
+// matches reports whether the position has been saved for the file.
+func (p position) matches(f io.ReaderAt) bool {
+	fp, n, err := fingerprint(f, p.FingerprintSize)
+	return err == nil && n == p.FingerprintSize && fp == p.Fingerprint
+}
+
+// state keeps the positions in the followed files of a trigger in a JSON file,
+// so that a restarted trigger resumes where it stopped.
+// A state without a path keeps nothing.
+type state struct {
+	path      string
+	mu        sync.Mutex
+	positions map[string]position
+}
+
+// loadState reads the state from the file, it is empty if the file does not exist.
+func loadState(path string) (*state, error) {
+	s := &state{path: path, positions: make(map[string]position)}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &s.positions)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *state) get(file string) (position, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.positions[file]
+	return p, ok
+}
+
+// remove forgets the position in the file, e.g. when the file is rotated.
+func (s *state) remove(file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.positions, file)
+}
+
+// set saves the position in the file. The state file is replaced atomically,
+// so that a crash does not leave it half-written.
+func (s *state) set(file string, p position) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positions[file] = p
+	if s.path == "" {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	err := json.NewEncoder(&buf).Encode(s.positions)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(buf.Bytes())
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
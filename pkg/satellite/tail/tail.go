@@ -0,0 +1,195 @@
+package tail
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Defaults of the TailConfig.
+const (
+	DefaultMaxLines         = 500
+	DefaultPollIntervalInMs = 250
+	DefaultFlushAfterInMs   = 1000
+)
+
+type TailConfig struct {
+	Path      []string `json:"path" required:"true" min:"1" desc:"Paths to the followed files."`
+	FromStart bool     `json:"fromStart" desc:"Reads the files from the beginning, unless resuming."`
+	Multiline string   `json:"multiline" desc:"Pattern matching the first line of a record, e.g. ^\\S."`
+	MaxLines  int      `json:"maxLines" min:"1" default:"500" desc:"Maximum number of lines joined in a record."`
+
+	PollIntervalInMs int `json:"pollIntervalInMs" min:"10" default:"250" desc:"How often the files are read."`
+	FlushAfterInMs   int `json:"flushAfterInMs" min:"1" default:"1000" desc:"Emits a record, when no line follows it."`
+}
+
+// Tailer follows files, the positions in which are kept in the state directory.
+type Tailer struct {
+	stateDir string
+}
+
+// Tail emits a message for every line, or record of joined lines, appended to the files.
+// With a multiline pattern, the lines that do not match it are joined to the preceding line.
+// The position in each file is saved after its lines are emitted, so that a restarted trigger
+// neither replays nor skips lines, except for the last lines emitted before a crash.
+func (t Tailer) Tail(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	c := config.(TailConfig)
+	multiline, err := c.multiline()
+	if err != nil {
+		return err
+	}
+	st, err := loadState(t.statePath(ctx))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg     sync.WaitGroup
+		once   sync.Once
+		runErr error
+	)
+	for _, path := range c.Path {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		f := &follower{
+			path:       path,
+			fromStart:  c.FromStart,
+			multiline:  multiline,
+			maxLines:   c.maxLines(),
+			poll:       ms(c.PollIntervalInMs, DefaultPollIntervalInMs),
+			flushAfter: ms(c.FlushAfterInMs, DefaultFlushAfterInMs),
+			state:      st,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ferr := f.run(ctx, func(r record) error {
+				return e.Emit(ctx, message(f.path, r))
+			})
+			if ferr != nil && ctx.Err() == nil {
+				once.Do(func() { runErr = ferr })
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return runErr
+}
+
+// statePath returns the path to the state file of the trigger, which is empty
+// if there is no state directory or the trigger has no ID.
+func (t Tailer) statePath(ctx context.Context) string {
+	id, _ := ctx.Value(satellite.ContextKeyTriggerID).(string)
+	if t.stateDir == "" || id == "" {
+		return ""
+	}
+	// The ID is hashed, since it is not necessarily a valid file name.
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(t.stateDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c TailConfig) multiline() (*regexp.Regexp, error) {
+	if c.Multiline == "" {
+		return nil, nil
+	}
+	return regexp.Compile(c.Multiline)
+}
+
+func (c TailConfig) maxLines() int {
+	if c.MaxLines <= 0 {
+		return DefaultMaxLines
+	}
+	return c.MaxLines
+}
+
+func ms(v, def int) time.Duration {
+	if v <= 0 {
+		v = def
+	}
+	return time.Duration(v) * time.Millisecond
+}
+
+func message(path string, r record) satellite.Message {
+	return satellite.Message{
+		"file":   path,
+		"offset": r.offset,
+		"line":   strings.Join(r.lines, "\n"),
+	}
+}
+
+// ValidateTail checks that the multiline pattern is valid and that the directories of the files exist.
+// The files themselves may not exist yet.
+func ValidateTail(ctx context.Context, config interface{}) error {
+	c := config.(TailConfig)
+
+	var errs schema.ValidationErrors
+	for i, path := range c.Path {
+		key := fmt.Sprintf("path[%d]", i)
+		info, err := os.Stat(filepath.Dir(path))
+		switch {
+		case err != nil:
+			errs = append(errs, schema.ValidationError{Path: key, Message: dirError(err)})
+		case !info.IsDir():
+			errs = append(errs, schema.ValidationError{Path: key, Message: "is not in a directory"})
+		}
+	}
+	if _, err := c.multiline(); err != nil {
+		errs = append(errs, schema.ValidationError{Path: "multiline", Message: "is not a valid pattern"})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// dirError describes an error returned by os.Stat for the directory of a file.
+func dirError(err error) string {
+	switch {
+	case os.IsNotExist(err):
+		return "directory does not exist"
+	case os.IsPermission(err):
+		return "directory is not accessible"
+	}
+	return err.Error()
+}
+
+// TailFields describes the messages emitted by the trigger.
+func TailFields() schema.Fields {
+	return schema.Fields{
+		"file": &schema.Field{
+			Name:        "File",
+			Type:        schema.String,
+			Description: "Absolute path to the file",
+			Required:    true,
+		},
+		"offset": &schema.Field{
+			Name:        "Offset",
+			Type:        schema.Integer,
+			Description: "Offset of the line in the file, in bytes",
+			Required:    true,
+		},
+		"line": &schema.Field{
+			Name:        "Line",
+			Type:        schema.String,
+			Description: "Line without the line break, or the joined lines of a multiline record",
+			Required:    true,
+		},
+	}
+}
@@ -0,0 +1,110 @@
+package satellite
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Template delimiters.
+const (
+	TemplateOpen  = "{{"
+	TemplateClose = "}}"
+)
+
+// ErrUnclosedPlaceholder is returned when a Template has "{{" without "}}".
+var ErrUnclosedPlaceholder = errors.New("template: unclosed placeholder")
+
+// Template is a text with placeholders of message fields,
+// e.g. "Issue {{issue.key}} is {{issue.status}}", see Message.Get for the paths.
+// Absent fields are rendered empty, objects and collections are rendered as JSON.
+type Template string
+
+// Fields returns the paths of the placeholders.
+func (t Template) Fields() ([]string, error) {
+	var paths []string
+	err := t.walk(func(text string, placeholder bool) {
+		if placeholder {
+			paths = append(paths, text)
+		}
+	})
+	return paths, err
+}
+
+// Render replaces the placeholders with the message fields.
+func (t Template) Render(m Message) (string, error) {
+	var b bytes.Buffer
+	var err error
+	walkErr := t.walk(func(text string, placeholder bool) {
+		if !placeholder {
+			b.WriteString(text)
+			return
+		}
+		v, _ := m.Get(text)
+		s, e := Format(v)
+		if e != nil && err == nil {
+			err = e
+		}
+		b.WriteString(s)
+	})
+	if walkErr != nil {
+		return "", walkErr
+	}
+	return b.String(), err
+}
+
+// IsConstant reports whether the template has no placeholders.
+func (t Template) IsConstant() bool {
+	return !strings.Contains(string(t), TemplateOpen)
+}
+
+func (t Template) walk(f func(text string, placeholder bool)) error {
+	s := string(t)
+	for {
+		i := strings.Index(s, TemplateOpen)
+		if i < 0 {
+			f(s, false)
+			return nil
+		}
+		f(s[:i], false)
+		s = s[i+len(TemplateOpen):]
+
+		j := strings.Index(s, TemplateClose)
+		if j < 0 {
+			return ErrUnclosedPlaceholder
+		}
+		f(strings.TrimSpace(s[:j]), true)
+		s = s[j+len(TemplateClose):]
+	}
+}
+
+// Format formats a field value of a message as text. Nil is formatted as an empty string,
+// times as RFC 3339, objects and collections as JSON.
+func Format(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case json.Number:
+		return v.String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32:
+		return fmt.Sprint(v), nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -13,7 +13,21 @@ import (
 type TransportConfig struct {
 	Adapter              string `required:"true"`
 	Redis                redis.Config
-	RegisterTimeoutInSec int `default:"10000"`
+	RegisterTimeoutInSec int `default:"10"`
+
+	// ReregisterIntervalInSec is how often the satellite registers again
+	// after it has been started.
+	ReregisterIntervalInSec int `default:"30"`
+
+	// PollTimeoutInMs limits the time of waiting for new requests.
+	PollTimeoutInMs int `default:"2000"`
+
+	// ShutdownTimeoutInMs limits the time of waiting for the in-flight requests on Stop.
+	ShutdownTimeoutInMs int `default:"30000"`
+
+	// Namespace isolates the topics of a tenant sharing the message broker
+	// with other tenants. It must match one of the space center namespaces.
+	Namespace string
 }
 
 const (
@@ -0,0 +1,131 @@
+package satellite
+
+import (
+	"context"
+
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// ValidateConfig checks the JSON encoded config of the named ability of the kind,
+// e.g. when a mission step is saved. The config is validated against the ability's ConfigFields,
+// then it is decoded and passed to the ability's Validator, if any.
+// It returns schema.ValidationErrors describing the invalid fields of the config.
+// The Validator may check the config against the incoming messages, see ContextKeyInput.
+func (s *Satellite) ValidateConfig(ctx context.Context, kind Kind, name string, config []byte) error {
+	a, ok := s.abilityConfig(kind, name)
+	if !ok {
+		return ErrUnknownAbility
+	}
+	_, err := a.decode(ctx, config)
+	return err
+}
+
+// DescribeOutput validates the config like ValidateConfig and describes the messages the ability outputs
+// with it, e.g. the fields a modifier adds to the incoming messages described by ContextKeyInput.
+// It returns the ability's Output unless the ability has OutputFunc.
+func (s *Satellite) DescribeOutput(ctx context.Context, kind Kind, name string, config []byte) (schema.Fields, error) {
+	a, ok := s.abilityConfig(kind, name)
+	if !ok {
+		return nil, ErrUnknownAbility
+	}
+	decoded, err := a.decode(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if a.outputFunc == nil {
+		return a.output, nil
+	}
+	input, _ := ctx.Value(ContextKeyInput).(schema.Fields)
+	return a.outputFunc(ctx, decoded, input)
+}
+
+// abilityConfig is what it takes to validate and decode the config of an ability.
+type abilityConfig struct {
+	prototype interface{}
+	fields    schema.Fields
+	input     schema.Fields
+	validator ValidatorFunc
+
+	output     schema.Fields
+	outputFunc OutputFunc
+}
+
+func (s *Satellite) abilityConfig(kind Kind, name string) (abilityConfig, bool) {
+	switch kind {
+	case KindTrigger:
+		for _, t := range s.Triggers {
+			if t.Info.Name == name {
+				return abilityConfig{t.Config, t.ConfigFields, nil, t.Validator, t.Output, t.OutputFunc}, true
+			}
+		}
+	case KindFilter:
+		for _, f := range s.Filters {
+			if f.Info.Name == name {
+				return abilityConfig{f.Config, f.ConfigFields, f.Input, f.Validator, f.Output, f.OutputFunc}, true
+			}
+		}
+	case KindModifier:
+		for _, m := range s.Modifiers {
+			if m.Info.Name == name {
+				return abilityConfig{m.Config, m.ConfigFields, m.Input, m.Validator, m.Output, m.OutputFunc}, true
+			}
+		}
+	case KindAction:
+		for _, a := range s.Actions {
+			if a.Info.Name == name {
+				return abilityConfig{a.Config, a.ConfigFields, a.Input, a.Validator, a.Output, a.OutputFunc}, true
+			}
+		}
+	case KindSplitter:
+		for _, sp := range s.Splitters {
+			if sp.Info.Name == name {
+				return abilityConfig{sp.Config, sp.ConfigFields, sp.Input, sp.Validator, sp.Output, sp.OutputFunc}, true
+			}
+		}
+	}
+	return abilityConfig{}, false
+}
+
+// decode validates the JSON encoded config and returns it decoded.
+func (a abilityConfig) decode(ctx context.Context, data []byte) (interface{}, error) {
+	err := schema.ValidateJSON(a.fields, data)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := DecodeConfig(a.prototype, data)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.validator != nil {
+		err = a.validator(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+// decodeWith decodes the config like decode and validates the incoming message against the input.
+// The validation errors of both are returned together, prefixed by "config" and "message".
+func (a abilityConfig) decodeWith(ctx context.Context, data []byte, m Message) (interface{}, error) {
+	var errs schema.ValidationErrors
+
+	err := schema.Validate(a.input, m)
+	if err != nil {
+		errs = append(errs, err.(schema.ValidationErrors).Prefix("message")...)
+	}
+
+	config, err := a.decode(ctx, data)
+	if verrs, ok := err.(schema.ValidationErrors); ok {
+		errs = append(errs, verrs.Prefix("config")...)
+	} else if err != nil && len(errs) == 0 {
+		return nil, err
+	}
+
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return config, nil
+}
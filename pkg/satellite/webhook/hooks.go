@@ -0,0 +1,395 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/schema"
+)
+
+// Responses of the webhooks.
+const (
+	// ResponseSync responds after the message has been emitted.
+	ResponseSync = "sync"
+	// ResponseAsync responds with 202 Accepted before the message is emitted.
+	ResponseAsync = "async"
+)
+
+// Defaults of the HookConfig.
+const (
+	DefaultSignatureHeader = "X-Signature"
+	DefaultToleranceInSec  = 300
+)
+
+// ErrPathInUse is returned when a webhook is started on the path of another running webhook.
+var ErrPathInUse = errors.New("path is used by another webhook")
+
+// errClosed is the error of a sync request received after the trigger has stopped.
+var errClosed = errors.New("webhook is closed")
+
+type HookConfig struct {
+	Path    string   `json:"path" required:"true" pattern:"^/" examples:"/hooks/github" desc:"Path of the endpoint."`
+	Methods []string `json:"methods" examples:"POST,PUT" desc:"Accepted HTTP methods, defaults to POST."`
+
+	Signature       string `json:"signature" enum:"none,github,stripe,hmac-sha256" default:"none" desc:"Signature scheme."`
+	Secret          string `json:"secret" secret:"true" desc:"Secret key of the signatures."`
+	SignatureHeader string `json:"signatureHeader" default:"X-Signature" desc:"Header of hmac-sha256 signatures."`
+	ToleranceInSec  int    `json:"toleranceInSec" min:"1" default:"300" desc:"Maximum age of stripe signatures."`
+
+	Response     string `json:"response" enum:"sync,async" default:"sync" desc:"Responds after or before emitting."`
+	ResponseBody string `json:"responseBody" examples:"{\"ok\":true}" desc:"Template of the sync response body."`
+}
+
+// Hooks is the http.Handler serving the webhooks of the running triggers.
+type Hooks struct {
+	maxBodyBytes int64
+	queueSize    int
+
+	mu     sync.Mutex
+	routes map[string]*route
+}
+
+// NewHooks returns the handler, which serves nothing until the triggers are started.
+func NewHooks(c Config) *Hooks {
+	return &Hooks{
+		maxBodyBytes: c.MaxBodyBytes,
+		queueSize:    c.QueueSize,
+		routes:       make(map[string]*route),
+	}
+}
+
+// route is a webhook of a running trigger.
+type route struct {
+	config HookConfig
+	ctx    context.Context
+	e      satellite.Emitter
+
+	// mu guards the queue and the sync emits, which are closed when the trigger stops.
+	mu     sync.RWMutex
+	closed bool
+	queue  chan satellite.Message
+}
+
+// Hook serves the webhook until the context is canceled. Every request is converted into a message.
+// A sync webhook responds with 503 Service Unavailable if the message cannot be emitted,
+// so that the sender retries. An async webhook has already responded, so the trigger
+// stops with the error instead.
+func (h *Hooks) Hook(ctx context.Context, config interface{}, e satellite.Emitter) error {
+	c := config.(HookConfig)
+	r := &route{config: c, ctx: ctx, e: e, queue: make(chan satellite.Message, h.queueSize)}
+
+	err := h.add(c.Path, r)
+	if err != nil {
+		return err
+	}
+	defer h.remove(c.Path)
+
+	for {
+		select {
+		case m := <-r.queue:
+			err = e.Emit(ctx, m)
+			if err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return r.drain()
+		}
+	}
+}
+
+func (h *Hooks) add(path string, r *route) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.routes[path]; ok {
+		return ErrPathInUse
+	}
+	h.routes[path] = r
+	return nil
+}
+
+func (h *Hooks) remove(path string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.routes, path)
+}
+
+// drain closes the queue and emits the accepted messages.
+func (r *route) drain() error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	for {
+		select {
+		case m := <-r.queue:
+			// The trigger's context is canceled already.
+			err := r.e.Emit(context.Background(), m)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// ServeHTTP serves the webhook of the request path.
+func (h *Hooks) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	r, ok := h.routes[req.URL.Path]
+	h.mu.Unlock()
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	if !r.config.allows(req.Method) {
+		w.Header().Set("Allow", strings.Join(r.config.methods(), ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, req.Body, h.maxBodyBytes))
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	err = verify(r.config, req.Header, body, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	m, err := message(req, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if r.config.Response == ResponseAsync {
+		r.enqueue(w, m)
+		return
+	}
+	r.emit(w, m)
+}
+
+// enqueue responds with 202 Accepted, if the message has been queued to be emitted.
+func (r *route) enqueue(w http.ResponseWriter, m satellite.Message) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	select {
+	case r.queue <- m:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "too many requests", http.StatusServiceUnavailable)
+	}
+}
+
+// emit responds with the rendered response body, if the message has been emitted.
+// The message is not emitted once the trigger stops, the stop waits for the emits in progress.
+func (r *route) emit(w http.ResponseWriter, m satellite.Message) {
+	r.mu.RLock()
+	err := errClosed
+	if !r.closed {
+		err = r.e.Emit(r.ctx, m)
+	}
+	r.mu.RUnlock()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+	if r.config.ResponseBody == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	body, err := satellite.Template(r.config.ResponseBody).Render(m)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var v interface{}
+	if json.Unmarshal([]byte(body), &v) == nil {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(body)) // nolint: errcheck
+}
+
+// message converts the request into a message. Multiple values of a header are joined with commas,
+// only the first value of a query or form parameter is kept.
+func message(req *http.Request, body []byte) (satellite.Message, error) {
+	headers := make(map[string]interface{}, len(req.Header))
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+
+	m := satellite.Message{
+		"method":     req.Method,
+		"path":       req.URL.Path,
+		"headers":    headers,
+		"query":      first(req.URL.Query()),
+		"rawBody":    string(body),
+		"remoteAddr": req.RemoteAddr,
+	}
+	if len(body) == 0 {
+		return m, nil
+	}
+
+	typ, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch {
+	case typ == "application/json" || strings.HasSuffix(typ, "+json"):
+		var v interface{}
+		err := json.Unmarshal(body, &v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %v", err)
+		}
+		if obj, ok := v.(map[string]interface{}); ok {
+			m["body"] = obj
+		} else {
+			// Arrays and scalars are wrapped, so that the body is always an object.
+			m["body"] = map[string]interface{}{"body": v}
+		}
+	case typ == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid form: %v", err)
+		}
+		m["body"] = first(form)
+	}
+	return m, nil
+}
+
+func first(values url.Values) map[string]interface{} {
+	m := make(map[string]interface{}, len(values))
+	for name, v := range values {
+		if len(v) > 0 {
+			m[name] = v[0]
+		}
+	}
+	return m
+}
+
+func (c HookConfig) methods() []string {
+	if len(c.Methods) == 0 {
+		return []string{http.MethodPost}
+	}
+	return c.Methods
+}
+
+func (c HookConfig) allows(method string) bool {
+	for _, m := range c.methods() {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c HookConfig) signatureHeader() string {
+	if c.SignatureHeader == "" {
+		return DefaultSignatureHeader
+	}
+	return c.SignatureHeader
+}
+
+func (c HookConfig) tolerance() time.Duration {
+	if c.ToleranceInSec <= 0 {
+		return DefaultToleranceInSec * time.Second
+	}
+	return time.Duration(c.ToleranceInSec) * time.Second
+}
+
+// ValidateHook checks the path, the methods, the secret of a signed webhook and the response template.
+func ValidateHook(ctx context.Context, config interface{}) error {
+	c := config.(HookConfig)
+
+	var errs schema.ValidationErrors
+	if c.Path != "" && path.Clean(c.Path) != c.Path {
+		errs = append(errs, schema.ValidationError{Path: "path", Message: "must be a clean path, e.g. " + path.Clean(c.Path)})
+	}
+	for i, m := range c.Methods {
+		if m == "" || strings.ContainsAny(m, " \t/()<>@,;:\\\"[]?={}") {
+			errs = append(errs, schema.ValidationError{Path: fmt.Sprintf("methods[%d]", i), Message: "is not a valid method"})
+		}
+	}
+	if c.Signature != "" && c.Signature != SignatureNone && c.Secret == "" {
+		errs = append(errs, schema.ValidationError{Path: "secret", Message: "is required to verify signatures"})
+	}
+	if _, err := satellite.Template(c.ResponseBody).Fields(); err != nil {
+		errs = append(errs, schema.ValidationError{Path: "responseBody", Message: err.Error()})
+	}
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+		return errs
+	}
+	return nil
+}
+
+// HookFields describes the messages emitted by the trigger.
+func HookFields() schema.Fields {
+	return schema.Fields{
+		"method": &schema.Field{
+			Name:        "Method",
+			Type:        schema.String,
+			Description: "HTTP method of the request, e.g. POST",
+			Required:    true,
+		},
+		"path": &schema.Field{
+			Name:        "Path",
+			Type:        schema.String,
+			Description: "Path of the request",
+			Required:    true,
+		},
+		"headers": &schema.Field{
+			Name:        "Headers",
+			Type:        schema.Object,
+			Description: "Headers of the request by their lower case names, e.g. headers.content-type",
+			Required:    true,
+		},
+		"query": &schema.Field{
+			Name:        "Query",
+			Type:        schema.Object,
+			Description: "Query parameters of the request",
+			Required:    true,
+		},
+		"body": &schema.Field{
+			Name:        "Body",
+			Type:        schema.Object,
+			Description: "Form fields or JSON of the body, non-objects as {\"body\": ...}, absent for other content types",
+		},
+		"rawBody": &schema.Field{
+			Name:        "Raw body",
+			Type:        schema.String,
+			Description: "Body of the request as is",
+			Required:    true,
+		},
+		"remoteAddr": &schema.Field{
+			Name:        "Remote address",
+			Type:        schema.String,
+			Description: "Network address of the sender, e.g. 192.0.2.1:51234",
+			Required:    true,
+		},
+	}
+}
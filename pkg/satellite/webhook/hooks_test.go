@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+)
+
+func TestMessageBody(t *testing.T) {
+	tests := []struct {
+		typ  string
+		body string
+		want interface{}
+	}{
+		{typ: "application/json", body: `{"a":1}`, want: map[string]interface{}{"a": 1.0}},
+		{typ: "application/json", body: `[1,2]`, want: map[string]interface{}{"body": []interface{}{1.0, 2.0}}},
+		{typ: "application/vnd.api+json", body: `"ok"`, want: map[string]interface{}{"body": "ok"}},
+		{typ: "application/x-www-form-urlencoded", body: "a=1&a=2&b=3", want: map[string]interface{}{"a": "1", "b": "3"}},
+		{typ: "text/plain", body: "hello"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/hook", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.typ)
+		m, err := message(req, []byte(tt.body))
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.typ, tt.body, err)
+			continue
+		}
+		if !reflect.DeepEqual(m["body"], tt.want) {
+			t.Errorf("%s %s: got %#v, want %#v", tt.typ, tt.body, m["body"], tt.want)
+		}
+		if m["rawBody"] != tt.body {
+			t.Errorf("%s %s: got raw body %v", tt.typ, tt.body, m["rawBody"])
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/hook", nil)
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := message(req, []byte("{")); err == nil {
+		t.Error("invalid JSON: no error")
+	}
+}
+
+func TestEmitAfterStop(t *testing.T) {
+	emitted := 0
+	r := &route{
+		ctx: context.Background(),
+		e: satellite.EmitterFunc(func(ctx context.Context, m satellite.Message) error {
+			emitted++
+			return nil
+		}),
+		queue: make(chan satellite.Message, 1),
+	}
+
+	w := httptest.NewRecorder()
+	r.emit(w, satellite.Message{})
+	if w.Code != http.StatusOK || emitted != 1 {
+		t.Errorf("got %d, emitted %d", w.Code, emitted)
+	}
+
+	if err := r.drain(); err != nil {
+		t.Fatal(err)
+	}
+	w = httptest.NewRecorder()
+	r.emit(w, satellite.Message{})
+	if w.Code != http.StatusServiceUnavailable || emitted != 1 {
+		t.Errorf("got %d, emitted %d", w.Code, emitted)
+	}
+}
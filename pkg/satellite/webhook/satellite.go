@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"github.com/antonkuzmenko/gogarin/pkg/satellite"
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+)
+
+// Info describes the satellite.
+var Info = satellite.Info{
+	Name:        "HTTP",
+	Version:     "0.1.0-alpha",
+	Description: "Triggers missions by HTTP callbacks (webhooks).",
+}
+
+// Config configures the satellite, e.g. with GOGARIN_SATELLITE_HTTP_ADDRESS=:8080.
+type Config struct {
+	// Address is the TCP address the webhooks are served on.
+	Address string `default:":8080"`
+
+	// MaxBodyBytes limits the size of the request bodies.
+	// The default MaxBodyBytes is 1048576/1MiB.
+	MaxBodyBytes int64 `default:"1048576"`
+
+	// QueueSize limits the number of the requests of an async webhook waiting to be emitted.
+	// When the queue is full, the webhook responds with 503 Service Unavailable.
+	QueueSize int `default:"100"`
+
+	// ReadTimeoutInMs limits the time of reading a request, including its body.
+	ReadTimeoutInMs int `default:"10000"`
+
+	// WriteTimeoutInMs limits the time of responding to a request,
+	// including emitting the message of a sync webhook.
+	WriteTimeoutInMs int `default:"10000"`
+}
+
+// New returns the satellite with all its abilities. The webhooks of its triggers
+// are served by the hooks, which must be served over HTTP, see NewHooks.
+func New(conn transport.Connection, hooks *Hooks, options ...satellite.Option) *satellite.Satellite {
+	sat := satellite.New(conn, Info, options...)
+
+	sat.AddTrigger(
+		satellite.Trigger{
+			Call: hooks.Hook,
+			Info: satellite.AbilityInfo{
+				Name:        "Webhook",
+				Description: "Triggers when an HTTP request is sent to the webhook.",
+			},
+			Config:    HookConfig{},
+			Output:    HookFields(),
+			Validator: ValidateHook,
+		},
+	)
+
+	return sat
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature schemes of the webhooks.
+const (
+	// SignatureNone accepts unsigned requests.
+	SignatureNone = "none"
+	// SignatureGitHub verifies the X-Hub-Signature-256 header, e.g. sha256=<hex>.
+	SignatureGitHub = "github"
+	// SignatureStripe verifies the Stripe-Signature header, e.g. t=<unix time>,v1=<hex>,
+	// the timestamp of which must be within the tolerance.
+	SignatureStripe = "stripe"
+	// SignatureHMACSHA256 verifies the hex encoded HMAC-SHA256 of the body in the configured header.
+	SignatureHMACSHA256 = "hmac-sha256"
+)
+
+// Headers of the signatures.
+const (
+	GitHubSignatureHeader = "X-Hub-Signature-256"
+	StripeSignatureHeader = "Stripe-Signature"
+)
+
+var (
+	// ErrMissingSignature is returned when a request of a signed webhook has no signature.
+	ErrMissingSignature = errors.New("missing signature")
+	// ErrInvalidSignature is returned when the signature does not match the request.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrExpiredSignature is returned when the timestamp of the signature is out of the tolerance.
+	ErrExpiredSignature = errors.New("signature timestamp is out of the tolerance")
+)
+
+// verify checks the signature of the request body according to the scheme of the config.
+func verify(c HookConfig, h http.Header, body []byte, now time.Time) error {
+	switch c.Signature {
+	case "", SignatureNone:
+		return nil
+	case SignatureGitHub:
+		return verifyHex(c.Secret, h.Get(GitHubSignatureHeader), body)
+	case SignatureStripe:
+		return verifyStripe(c.Secret, h.Get(StripeSignatureHeader), body, c.tolerance(), now)
+	case SignatureHMACSHA256:
+		return verifyHex(c.Secret, h.Get(c.signatureHeader()), body)
+	}
+	return errors.New("unknown signature scheme " + c.Signature)
+}
+
+// verifyHex checks a hex encoded signature, which may be prefixed with sha256=.
+func verifyHex(secret, signature string, body []byte) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+	if !equalMAC(secret, strings.TrimPrefix(signature, "sha256="), body) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// verifyStripe checks a signature of the form t=<unix time>,v1=<hex>[,v1=<hex>],
+// where the signed payload is <unix time>.<body>.
+func verifyStripe(secret, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	var (
+		ts   string
+		sigs []string
+	)
+	for _, part := range strings.Split(signature, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sigs = append(sigs, kv[1])
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(sec, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+
+	payload := append([]byte(ts+"."), body...)
+	for _, sig := range sigs {
+		if equalMAC(secret, sig, payload) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// equalMAC compares the hex encoded MAC with the HMAC-SHA256 of the data in constant time.
+func equalMAC(secret, mac string, data []byte) bool {
+	got, err := hex.DecodeString(mac)
+	if err != nil {
+		return false
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write(data) // nolint: errcheck
+	return hmac.Equal(got, h.Sum(nil))
+}
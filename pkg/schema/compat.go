@@ -0,0 +1,147 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ChangeKind is the kind of a difference between two versions of a field.
+type ChangeKind string
+
+// Kinds of changes reported by Diff.
+const (
+	FieldAdded   ChangeKind = "added"
+	FieldRemoved ChangeKind = "removed"
+	FieldRetyped ChangeKind = "retyped"
+	// FieldRequired means that the field became required or not nullable.
+	FieldRequired ChangeKind = "required"
+	// FieldOptional means that the field became optional or nullable.
+	FieldOptional ChangeKind = "optional"
+)
+
+// Change is a difference between two versions of a field.
+// Path addresses the field, items of a collection are addressed with "[]", e.g. "items[].name".
+// Old is nil for added fields and New is nil for removed fields.
+//
+// Backward is true if the values valid under the old field are valid under the new one,
+// i.e. a consumer of the new version accepts the data produced by the old version.
+// Forward is true if the values valid under the new field are valid under the old one,
+// i.e. a consumer of the old version accepts the data produced by the new version.
+type Change struct {
+	Path     string
+	Kind     ChangeKind
+	Old      *Field
+	New      *Field
+	Backward bool
+	Forward  bool
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case FieldAdded:
+		return fmt.Sprintf("%s: added %s", c.Path, c.New.Type.Name)
+	case FieldRemoved:
+		return fmt.Sprintf("%s: removed %s", c.Path, c.Old.Type.Name)
+	case FieldRetyped:
+		return fmt.Sprintf("%s: retyped from %s to %s", c.Path, c.Old.Type.Name, c.New.Type.Name)
+	}
+	return fmt.Sprintf("%s: became %s", c.Path, c.Kind)
+}
+
+// Changes is a list of Change sorted by Path.
+type Changes []Change
+
+// Compatibility tells whether the data can be exchanged between two versions of fields,
+// see Change for the meaning of Backward and Forward.
+type Compatibility struct {
+	Backward bool
+	Forward  bool
+}
+
+// Compatibility returns the compatibility of the versions, the changes of which are listed.
+func (c Changes) Compatibility() Compatibility {
+	res := Compatibility{Backward: true, Forward: true}
+	for _, ch := range c {
+		res.Backward = res.Backward && ch.Backward
+		res.Forward = res.Forward && ch.Forward
+	}
+	return res
+}
+
+// Breaking returns the changes that break the consumers of the old version, e.g. the steps
+// of a mission referring to the fields of a message. Removed fields break the consumers even if
+// they were optional, the consumers might rely on them when they are present.
+func (c Changes) Breaking() Changes {
+	var res Changes
+	for _, ch := range c {
+		if !ch.Forward || ch.Kind == FieldRemoved {
+			res = append(res, ch)
+		}
+	}
+	return res
+}
+
+// Diff returns the added, removed and retyped fields, and the fields that became required
+// or optional in the new version. Nested objects and the items of collections are compared too.
+// Descriptions, defaults, examples and other constraints are not compared.
+func Diff(old, new Fields) Changes {
+	var c Changes
+	diffFields(old, new, "", &c)
+	sort.SliceStable(c, func(i, j int) bool { return c[i].Path < c[j].Path })
+	return c
+}
+
+func diffFields(old, new Fields, path string, c *Changes) {
+	for key, o := range old {
+		p := joinPath(path, key)
+		n, ok := new[key]
+		if !ok {
+			*c = append(*c, Change{Path: p, Kind: FieldRemoved, Old: o, Backward: true, Forward: !o.Required})
+			continue
+		}
+		diffField(o, n, p, c)
+	}
+
+	for key, n := range new {
+		if _, ok := old[key]; ok {
+			continue
+		}
+		*c = append(*c, Change{
+			Path:     joinPath(path, key),
+			Kind:     FieldAdded,
+			New:      n,
+			Backward: !n.Required,
+			Forward:  true,
+		})
+	}
+}
+
+func diffField(o, n *Field, path string, c *Changes) {
+	// Types are compared by name, their descriptions are not a part of the contract.
+	if o.Type.Name != n.Type.Name {
+		// Integers are valid floats, but not the other way around.
+		widened := o.Type.Name == Integer.Name && n.Type.Name == Float.Name
+		*c = append(*c, Change{Path: path, Kind: FieldRetyped, Old: o, New: n, Backward: widened})
+		return
+	}
+
+	// Null is accepted unless the field is required and not nullable, see Validate.
+	oldNull := !o.Required || o.Nullable
+	newNull := !n.Required || n.Nullable
+	switch {
+	case (n.Required && !o.Required) || (oldNull && !newNull):
+		*c = append(*c, Change{Path: path, Kind: FieldRequired, Old: o, New: n, Forward: true})
+	case (o.Required && !n.Required) || (newNull && !oldNull):
+		*c = append(*c, Change{Path: path, Kind: FieldOptional, Old: o, New: n, Backward: true})
+	}
+
+	switch o.Type {
+	case Object:
+		diffFields(o.Fields, n.Fields, path, c)
+	case Collection:
+		oe, ne := o.Elem(), n.Elem()
+		if oe != nil && ne != nil {
+			diffField(oe, ne, path+"[]", c)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package schema
+
+import "testing"
+
+func TestDiffTypeDescription(t *testing.T) {
+	old := Fields{"key": {Name: "Key", Type: String}}
+	new := Fields{"key": {Name: "Key", Type: NewFieldType("string", "Text, e.g. A-1")}}
+	if c := Diff(old, new); len(c) != 0 {
+		t.Errorf("got %v", c)
+	}
+
+	new = Fields{"key": {Name: "Key", Type: NewFieldType("float", "Number")}}
+	c := Diff(Fields{"key": {Name: "Key", Type: Integer}}, new)
+	if len(c) != 1 || c[0].Kind != FieldRetyped || !c[0].Backward {
+		t.Errorf("got %v", c)
+	}
+}
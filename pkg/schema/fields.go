@@ -1,23 +1,71 @@
 package schema
 
+import "encoding/json"
+
 type FieldType struct {
 	Name        string
 	Description string
 }
 
+// NewFieldType returns the field type with the name. The known field types are returned as is,
+// so that a type created with another description, e.g. before the known types were described,
+// equals the known one.
 func NewFieldType(name string, description string) FieldType {
+	if t, ok := TypeByName(name); ok {
+		return t
+	}
 	return FieldType{name, description}
 }
 
+// UnmarshalJSON decodes the field type like NewFieldType, e.g. the types of stored manifests.
+func (t *FieldType) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Name        string
+		Description string
+	}
+	err := json.Unmarshal(data, &v)
+	if err != nil {
+		return err
+	}
+	*t = NewFieldType(v.Name, v.Description)
+	return nil
+}
+
 var (
-	Boolean    = NewFieldType("boolean", "")
-	Integer    = NewFieldType("integer", "")
-	Float      = NewFieldType("float", "")
-	String     = NewFieldType("string", "")
-	Date       = NewFieldType("date", "")
-	Datetime   = NewFieldType("datetime", "")
-	Object     = NewFieldType("object", "")
-	Collection = NewFieldType("collection", "")
+	Boolean    = FieldType{"boolean", "true or false"}
+	Integer    = FieldType{"integer", "Whole number"}
+	Float      = FieldType{"float", "Floating point number"}
+	String     = FieldType{"string", "Text"}
+	Date       = FieldType{"date", "Calendar date, e.g. 2017-12-31"}
+	Datetime   = FieldType{"datetime", "Date and time with a time zone, e.g. 2017-12-31T23:59:59Z"}
+	Object     = FieldType{"object", "Set of named fields"}
+	Collection = FieldType{"collection", "List of items of the same type"}
+)
+
+// FieldTypes lists the known field types.
+var FieldTypes = []FieldType{Boolean, Integer, Float, String, Date, Datetime, Object, Collection}
+
+// TypeByName returns the known field type with the name.
+func TypeByName(name string) (FieldType, bool) {
+	for _, t := range FieldTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return FieldType{}, false
+}
+
+// Format refines a String field.
+type Format string
+
+// Known formats. Unknown formats are allowed but not validated.
+const (
+	// FormatEmail is an email address, e.g. user@example.com.
+	FormatEmail Format = "email"
+	// FormatURL is an absolute URL, e.g. https://example.com/path.
+	FormatURL Format = "url"
+	// FormatDuration is a Go duration, e.g. 1h30m, see time.ParseDuration.
+	FormatDuration Format = "go-duration"
 )
 
 type Fields map[string]*Field
@@ -26,5 +74,82 @@ type Field struct {
 	Name        string
 	Type        FieldType
 	Description string
-	Fields      Fields
+
+	// Fields describes the fields of an Object.
+	// For backward compatibility, it describes the items of a Collection if Items is nil.
+	Fields Fields
+	// Items describes the items of a Collection.
+	Items *Field
+
+	// Required fields must be present.
+	Required bool
+	// Nullable fields may be null, even if they are required.
+	Nullable bool
+	// Secret fields hold sensitive data, e.g. passwords, that must not be displayed or logged.
+	Secret bool
+	// Default is used when the field is absent.
+	Default interface{}
+	// Enum lists the allowed values.
+	Enum []interface{}
+	// Examples lists sample values.
+	Examples []interface{}
+	// Format refines a String, e.g. FormatEmail.
+	Format Format
+	// Pattern is a regular expression a String must match.
+	Pattern string
+	// Min and Max limit a number, or the length of a string or a collection.
+	Min *float64
+	Max *float64
+}
+
+// Elem returns the field describing the items of a Collection, or nil if the items are not described.
+func (f *Field) Elem() *Field {
+	if f.Items != nil {
+		return f.Items
+	}
+	if f.Fields != nil {
+		return &Field{Name: f.Name, Type: Object, Fields: f.Fields}
+	}
+	return nil
+}
+
+// Redact returns a copy of the decoded JSON object with the values of the Secret fields replaced,
+// so that the object can be logged.
+func Redact(f Fields, v map[string]interface{}) map[string]interface{} {
+	const redacted = "[REDACTED]"
+
+	res := make(map[string]interface{}, len(v))
+	for key, value := range v {
+		field, ok := f[key]
+		switch {
+		case !ok || value == nil:
+			res[key] = value
+		case field.Secret:
+			res[key] = redacted
+		case field.Type == Object:
+			if m, ok := object(value); ok {
+				res[key] = Redact(field.Fields, m)
+				continue
+			}
+			res[key] = value
+		case field.Type == Collection:
+			items, ok := collection(value)
+			elem := field.Elem()
+			if !ok || elem == nil || elem.Type != Object {
+				res[key] = value
+				continue
+			}
+			redactedItems := make([]interface{}, len(items))
+			for i, item := range items {
+				redactedItems[i] = item
+				if m, ok := object(item); ok {
+					redactedItems[i] = Redact(elem.Fields, m)
+				}
+			}
+			res[key] = redactedItems
+		default:
+			res[key] = value
+		}
+	}
+	return res
 }
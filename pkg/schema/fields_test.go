@@ -0,0 +1,89 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// legacy returns the field types as they were created before the known types were described.
+func legacy(name string) FieldType {
+	return NewFieldType(name, "")
+}
+
+func TestLegacyFieldTypes(t *testing.T) {
+	for _, typ := range FieldTypes {
+		if got := legacy(typ.Name); got != typ {
+			t.Errorf("got %+v, want %+v", got, typ)
+		}
+
+		var decoded FieldType
+		err := json.Unmarshal([]byte(`{"Name":"`+typ.Name+`","Description":""}`), &decoded)
+		if err != nil || decoded != typ {
+			t.Errorf("got %+v, %v, want %+v", decoded, err, typ)
+		}
+	}
+
+	custom := NewFieldType("custom", "")
+	if custom != (FieldType{Name: "custom"}) {
+		t.Errorf("got %+v", custom)
+	}
+}
+
+func TestLegacyValidate(t *testing.T) {
+	f := Fields{
+		"key":   {Name: "Key", Type: legacy("string")},
+		"issue": {Name: "Issue", Type: legacy("object"), Fields: Fields{"id": {Name: "ID", Type: legacy("integer")}}},
+	}
+	err := Validate(f, map[string]interface{}{"key": 42.0, "issue": map[string]interface{}{"id": "A-1"}})
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestLegacyDiff(t *testing.T) {
+	old := Fields{"issue": {Name: "Issue", Type: legacy("object"), Fields: Fields{
+		"key": {Name: "Key", Type: legacy("string"), Required: true},
+	}}}
+	new := Fields{"issue": {Name: "Issue", Type: Object, Fields: Fields{}}}
+
+	c := Diff(old, new)
+	if len(c) != 1 || c[0].Kind != FieldRemoved || c[0].Path != "issue.key" {
+		t.Errorf("got %v", c)
+	}
+}
+
+func TestLegacyRedact(t *testing.T) {
+	f := Fields{"auth": {Name: "Auth", Type: legacy("object"), Fields: Fields{
+		"password": {Name: "Password", Type: legacy("string"), Secret: true},
+	}}}
+	got := Redact(f, map[string]interface{}{"auth": map[string]interface{}{"password": "secret"}})
+	want := map[string]interface{}{"auth": map[string]interface{}{"password": "[REDACTED]"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestLegacyJSONSchema(t *testing.T) {
+	data, err := json.Marshal(Fields{"count": {Name: "Count", Type: legacy("integer"), Default: 3.0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var f Fields
+	if err = json.Unmarshal(data, &f); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = MarshalJSONSchema(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := UnmarshalJSONSchema(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["count"].Type != Integer || got["count"].Default != int64(3) {
+		t.Errorf("%s: got %+v", data, got["count"])
+	}
+}
@@ -0,0 +1,399 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema draft produced by ToJSONSchema.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSON Schema types and formats.
+const (
+	jsonNull     = "null"
+	jsonBoolean  = "boolean"
+	jsonInteger  = "integer"
+	jsonNumber   = "number"
+	jsonString   = "string"
+	jsonObject   = "object"
+	jsonArray    = "array"
+	jsonDate     = "date"
+	jsonDatetime = "date-time"
+	jsonURI      = "uri"
+
+	defsPrefix = "#/$defs/"
+)
+
+// anyTypes is the "type" of a field of the zero or an unknown FieldType, which may be any value.
+var anyTypes = JSONSchemaTypes{jsonArray, jsonBoolean, jsonNumber, jsonObject, jsonString}
+
+// JSONSchema is a subset of JSON Schema draft 2020-12 that is enough to describe Fields.
+type JSONSchema struct {
+	Schema      string                 `json:"$schema,omitempty"`
+	Ref         string                 `json:"$ref,omitempty"`
+	Defs        map[string]*JSONSchema `json:"$defs,omitempty"`
+	Type        JSONSchemaTypes        `json:"type,omitempty"`
+	Format      string                 `json:"format,omitempty"`
+	Title       string                 `json:"title,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Default     interface{}            `json:"default,omitempty"`
+	Enum        []interface{}          `json:"enum,omitempty"`
+	Examples    []interface{}          `json:"examples,omitempty"`
+	WriteOnly   bool                   `json:"writeOnly,omitempty"`
+	Pattern     string                 `json:"pattern,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Items       *JSONSchema            `json:"items,omitempty"`
+	Minimum     *float64               `json:"minimum,omitempty"`
+	Maximum     *float64               `json:"maximum,omitempty"`
+	MinLength   *float64               `json:"minLength,omitempty"`
+	MaxLength   *float64               `json:"maxLength,omitempty"`
+	MinItems    *float64               `json:"minItems,omitempty"`
+	MaxItems    *float64               `json:"maxItems,omitempty"`
+
+	// FieldType is a field type JSON Schema has no counterpart for, e.g. a custom one.
+	FieldType *FieldType `json:"x-fieldType,omitempty"`
+	// CollectionFields reports that the Items describe the Fields of a Collection rather than its Items.
+	CollectionFields bool `json:"x-collectionFields,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler. Unlike nil Properties, empty ones are encoded,
+// e.g. of an Object with empty Fields.
+func (s JSONSchema) MarshalJSON() ([]byte, error) {
+	type plain JSONSchema
+	data, err := json.Marshal(plain(s))
+	if err != nil || s.Properties == nil || len(s.Properties) > 0 {
+		return data, err
+	}
+
+	const properties = `"properties":{}`
+	if len(data) == len("{}") {
+		return []byte("{" + properties + "}"), nil
+	}
+	return append(data[:len(data)-1], []byte(","+properties+"}")...), nil
+}
+
+// JSONSchemaTypes is the value of the "type" keyword.
+// It is encoded as a string when there is a single type.
+type JSONSchemaTypes []string
+
+// MarshalJSON implements json.Marshaler.
+func (t JSONSchemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *JSONSchemaTypes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*t = JSONSchemaTypes{s}
+		return nil
+	}
+
+	var ss []string
+	err := json.Unmarshal(data, &ss)
+	if err != nil {
+		return err
+	}
+	*t = ss
+	return nil
+}
+
+// ToJSONSchema converts the fields to a JSON Schema of an object.
+// FromJSONSchema converts the schema back to the same fields.
+func ToJSONSchema(f Fields) *JSONSchema {
+	s := objectSchema(f)
+	s.Schema = JSONSchemaDialect
+	return s
+}
+
+// MarshalJSONSchema encodes the fields as a JSON Schema document.
+func MarshalJSONSchema(f Fields) ([]byte, error) {
+	return json.Marshal(ToJSONSchema(f))
+}
+
+func objectSchema(f Fields) *JSONSchema {
+	s := &JSONSchema{Type: JSONSchemaTypes{jsonObject}}
+	if f == nil {
+		return s
+	}
+
+	s.Properties = make(map[string]*JSONSchema, len(f))
+	for key, field := range f {
+		s.Properties[key] = fieldSchema(field)
+		if field.Required {
+			s.Required = append(s.Required, key)
+		}
+	}
+	sort.Strings(s.Required)
+	return s
+}
+
+func fieldSchema(f *Field) *JSONSchema {
+	var s *JSONSchema
+
+	switch f.Type {
+	case Boolean:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonBoolean}}
+	case Integer:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonInteger}, Minimum: f.Min, Maximum: f.Max}
+	case Float:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonNumber}, Minimum: f.Min, Maximum: f.Max}
+	case String:
+		s = &JSONSchema{
+			Type:      JSONSchemaTypes{jsonString},
+			Format:    jsonFormat(f.Format),
+			Pattern:   f.Pattern,
+			MinLength: f.Min,
+			MaxLength: f.Max,
+		}
+	case Date:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonString}, Format: jsonDate}
+	case Datetime:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonString}, Format: jsonDatetime}
+	case Object:
+		s = objectSchema(f.Fields)
+	case Collection:
+		s = &JSONSchema{Type: JSONSchemaTypes{jsonArray}, MinItems: f.Min, MaxItems: f.Max}
+		if f.Items != nil {
+			s.Items = fieldSchema(f.Items)
+		} else if f.Fields != nil {
+			s.Items = objectSchema(f.Fields)
+			s.CollectionFields = true
+		}
+	default:
+		s = &JSONSchema{Type: append(JSONSchemaTypes{}, anyTypes...)}
+		if f.Type != (FieldType{}) {
+			t := f.Type
+			s.FieldType = &t
+		}
+	}
+
+	if f.Nullable {
+		s.Type = append(s.Type, jsonNull)
+	}
+	s.Title = f.Name
+	s.Description = f.Description
+	s.Default = f.Default
+	s.Enum = f.Enum
+	s.Examples = f.Examples
+	s.WriteOnly = f.Secret
+	return s
+}
+
+// jsonFormat converts the Format to the JSON Schema "format" keyword.
+func jsonFormat(f Format) string {
+	if f == FormatURL {
+		return jsonURI
+	}
+	return string(f)
+}
+
+// FromJSONSchema converts a JSON Schema of an object to fields.
+// Local references to "#/$defs/..." are resolved.
+// Keywords that cannot be expressed with Fields are ignored.
+func FromJSONSchema(s *JSONSchema) (Fields, error) {
+	c := converter{defs: s.Defs, resolving: make(map[string]bool)}
+	s, err := c.resolve(s)
+	if err != nil {
+		return nil, err
+	}
+	if !s.Type.has(jsonObject) {
+		return nil, fmt.Errorf("schema: JSON Schema is not an object but %v", []string(s.Type))
+	}
+	return c.fields(s, "")
+}
+
+// UnmarshalJSONSchema decodes a JSON Schema document of an object to fields.
+func UnmarshalJSONSchema(data []byte) (Fields, error) {
+	var s JSONSchema
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return nil, err
+	}
+	return FromJSONSchema(&s)
+}
+
+func (t JSONSchemaTypes) has(typ string) bool {
+	for _, v := range t {
+		if v == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// single returns the type ignoring "null". It returns "" if there is no type or there are several,
+// then the values may be of any type.
+func (t JSONSchemaTypes) single() string {
+	var typ string
+	for _, v := range t {
+		if v == jsonNull {
+			continue
+		}
+		if typ != "" {
+			return ""
+		}
+		typ = v
+	}
+	return typ
+}
+
+type converter struct {
+	defs      map[string]*JSONSchema
+	resolving map[string]bool
+}
+
+func (c converter) resolve(s *JSONSchema) (*JSONSchema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	if !strings.HasPrefix(s.Ref, defsPrefix) {
+		return nil, fmt.Errorf("schema: unsupported $ref %s", s.Ref)
+	}
+	if c.resolving[s.Ref] {
+		return nil, fmt.Errorf("schema: recursive $ref %s", s.Ref)
+	}
+
+	def, ok := c.defs[strings.TrimPrefix(s.Ref, defsPrefix)]
+	if !ok {
+		return nil, fmt.Errorf("schema: unknown $ref %s", s.Ref)
+	}
+	return def, nil
+}
+
+func (c converter) fields(s *JSONSchema, parent string) (Fields, error) {
+	if s.Properties == nil {
+		return nil, nil
+	}
+
+	required := make(map[string]bool, len(s.Required))
+	for _, key := range s.Required {
+		required[key] = true
+	}
+
+	f := make(Fields, len(s.Properties))
+	for key, prop := range s.Properties {
+		field, err := c.field(key, prop, parent)
+		if err != nil {
+			return nil, err
+		}
+		field.Required = required[key]
+		f[key] = field
+	}
+	return f, nil
+}
+
+func (c converter) field(key string, s *JSONSchema, parent string) (*Field, error) {
+	ref := s.Ref
+	s, err := c.resolve(s)
+	if err != nil {
+		return nil, err
+	}
+	if ref != "" {
+		c.resolving[ref] = true
+		defer delete(c.resolving, ref)
+	}
+
+	f := &Field{
+		Name:        s.Title,
+		Description: s.Description,
+		Nullable:    len(s.Type) == 0 || s.Type.has(jsonNull),
+		Secret:      s.WriteOnly,
+	}
+	if f.Name == "" {
+		f.Name = key
+		if parent != "" {
+			f.Name = parent + "." + key
+		}
+	}
+
+	switch typ := s.Type.single(); typ {
+	case "":
+		// Any value, the zero FieldType unless it is an unknown one.
+	case jsonBoolean:
+		f.Type = Boolean
+	case jsonInteger:
+		f.Type = Integer
+		f.Min, f.Max = s.Minimum, s.Maximum
+	case jsonNumber:
+		f.Type = Float
+		f.Min, f.Max = s.Minimum, s.Maximum
+	case jsonString:
+		switch s.Format {
+		case jsonDate:
+			f.Type = Date
+		case jsonDatetime:
+			f.Type = Datetime
+		case jsonURI:
+			f.Type = String
+			f.Format = FormatURL
+		default:
+			f.Type = String
+			f.Format = Format(s.Format)
+		}
+		if f.Type == String {
+			f.Min, f.Max = s.MinLength, s.MaxLength
+			f.Pattern = s.Pattern
+		}
+	case jsonObject:
+		f.Type = Object
+		f.Fields, err = c.fields(s, f.Name)
+		if err != nil {
+			return nil, err
+		}
+	case jsonArray:
+		f.Type = Collection
+		f.Min, f.Max = s.MinItems, s.MaxItems
+		if s.Items != nil {
+			items, err := c.resolve(s.Items)
+			if err != nil {
+				return nil, err
+			}
+			// Items without a type are not described.
+			if len(items.Type) == 0 && items.FieldType == nil {
+				break
+			}
+			elem, err := c.field(key, s.Items, parent)
+			if err != nil {
+				return nil, err
+			}
+			if s.CollectionFields && elem.Type == Object {
+				f.Fields = elem.Fields
+			} else {
+				f.Items = elem
+			}
+		}
+	default:
+		return nil, fmt.Errorf("schema: property %s: unsupported JSON Schema type %q", key, typ)
+	}
+	if s.FieldType != nil {
+		f.Type = *s.FieldType
+	}
+
+	f.Default = value(f.Type, s.Default)
+	for _, e := range s.Enum {
+		f.Enum = append(f.Enum, value(f.Type, e))
+	}
+	for _, e := range s.Examples {
+		f.Examples = append(f.Examples, value(f.Type, e))
+	}
+	return f, nil
+}
+
+// value converts a decoded JSON number to the Go type used by the field type.
+func value(t FieldType, v interface{}) interface{} {
+	n, ok := v.(float64)
+	if !ok {
+		return v
+	}
+	if t == Integer {
+		return int64(n)
+	}
+	return n
+}
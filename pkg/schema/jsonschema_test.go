@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	one, ten := 1.0, 10.0
+	custom := NewFieldType("custom", "Custom type")
+
+	fields := Fields{
+		"boolean": {Name: "Boolean", Type: Boolean, Default: true, Required: true},
+		"integer": {
+			Name: "Integer", Type: Integer, Min: &one, Max: &ten,
+			Default: int64(3), Enum: []interface{}{int64(3), int64(5)}, Examples: []interface{}{int64(5)},
+		},
+		"float": {Name: "Float", Type: Float, Nullable: true, Min: &one, Default: 2.5},
+		"string": {
+			Name: "String", Type: String, Description: "Text", Secret: true,
+			Format: FormatEmail, Pattern: "^.+@.+$", Min: &one, Max: &ten,
+		},
+		"url":      {Name: "URL", Type: String, Format: FormatURL},
+		"date":     {Name: "Date", Type: Date, Examples: []interface{}{"2017-12-31"}},
+		"datetime": {Name: "Datetime", Type: Datetime, Required: true, Nullable: true},
+		"object": {Name: "Object", Type: Object, Fields: Fields{
+			"key": {Name: "Key", Type: String, Required: true},
+		}},
+		"emptyObject": {Name: "Empty object", Type: Object, Fields: Fields{}},
+		"anyObject":   {Name: "Any object", Type: Object},
+		"collection": {Name: "Collection", Type: Collection, Min: &one, Items: &Field{
+			Name: "Item", Type: Integer,
+		}},
+		"legacyCollection": {Name: "Legacy collection", Type: Collection, Fields: Fields{
+			"key": {Name: "Key", Type: String},
+		}},
+		"emptyCollection": {Name: "Empty collection", Type: Collection, Fields: Fields{}},
+		"anyCollection":   {Name: "Any collection", Type: Collection},
+		"untypedItems":    {Name: "Untyped items", Type: Collection, Items: &Field{Name: "Item"}},
+		"untyped":         {Name: "Untyped", Required: true},
+		"nullableUntyped": {Name: "Nullable untyped", Nullable: true},
+		"custom":          {Name: "Custom", Type: custom, Nullable: true},
+	}
+
+	for _, typ := range FieldTypes {
+		found := false
+		for _, f := range fields {
+			found = found || f.Type == typ
+		}
+		if !found {
+			t.Fatalf("no field of the %s type", typ.Name)
+		}
+	}
+
+	for _, f := range []Fields{fields, {}, nil} {
+		data, err := MarshalJSONSchema(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := UnmarshalJSONSchema(data)
+		if err != nil {
+			t.Fatalf("%s: %v", data, err)
+		}
+		if !reflect.DeepEqual(got, f) {
+			for key := range f {
+				if !reflect.DeepEqual(got[key], f[key]) {
+					t.Errorf("%s: got %+v, want %+v", key, got[key], f[key])
+				}
+			}
+			if len(f) == 0 {
+				t.Errorf("got %#v, want %#v", got, f)
+			}
+		}
+	}
+}
+
+func TestFromJSONSchemaUntyped(t *testing.T) {
+	var s JSONSchema
+	err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"properties": {
+			"any": {},
+			"union": {"type": ["string", "number"]},
+			"list": {"type": "array", "items": {}}
+		}
+	}`), &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FromJSONSchema(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Fields{
+		"any":   {Name: "any", Nullable: true},
+		"union": {Name: "union"},
+		"list":  {Name: "list", Type: Collection},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
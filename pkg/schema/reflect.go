@@ -0,0 +1,337 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Struct tags read by FieldsOf.
+const (
+	tagJSON        = "json"
+	tagName        = "name"
+	tagDescription = "desc"
+	tagRequired    = "required"
+	tagDefault     = "default"
+	tagEnum        = "enum"
+	tagMin         = "min"
+	tagMax         = "max"
+	tagFormat      = "format"
+	tagPattern     = "pattern"
+	tagNullable    = "nullable"
+	tagSecret      = "secret"
+	tagExamples    = "examples"
+
+	listSeparator  = ","
+	formatDate     = "date"
+	formatDuration = "duration"
+)
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// FieldsOf derives Fields from a struct, e.g. the config of an ability.
+// The keys of Fields are the names used by encoding/json.
+//
+// The following struct tags are supported:
+//   - name:"Human name", defaults to the struct field's name;
+//   - desc:"Description of the field";
+//   - required:"true";
+//   - default:"value", parsed according to the field's type;
+//   - enum:"a,b,c", the allowed values, parsed according to the field's type;
+//   - min:"1" and max:"10", the limits of a number, or of a string or a collection length;
+//   - examples:"a,b", sample values, parsed according to the field's type;
+//   - pattern:"^[a-z]+$", a regular expression a string must match;
+//   - nullable:"true", pointers are nullable by default;
+//   - secret:"true", e.g. for passwords and tokens;
+//   - format:"date", makes time.Time or a string a Date instead of a Datetime or a String;
+//   - format:"duration", a string holding a Go duration such as "1h30m";
+//   - format:"email", format:"url" or any other Format of a string.
+//
+// Nested and embedded structs, pointers, slices, arrays and maps are supported.
+// The items of slices and arrays are described by Items.
+// A nil config has no fields.
+func FieldsOf(config interface{}) (Fields, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	t := indirect(reflect.TypeOf(config))
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %s is not a struct", t)
+	}
+	return structFields(t, "", make(map[reflect.Type]bool))
+}
+
+// MustFieldsOf is like FieldsOf but panics if the struct cannot be described.
+func MustFieldsOf(config interface{}) Fields {
+	f, err := FieldsOf(config)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// structFields describes the fields of the struct type. visiting holds the struct
+// types being described, it prevents infinite recursion on recursive types.
+func structFields(t reflect.Type, parent string, visiting map[reflect.Type]bool) (Fields, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("recursive type %s", t)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	fields := make(Fields)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			// Unexported.
+			continue
+		}
+
+		key, skip := jsonKey(sf)
+		if skip {
+			continue
+		}
+
+		ft := indirect(sf.Type)
+		if sf.Anonymous && ft.Kind() == reflect.Struct && sf.Tag.Get(tagJSON) == "" {
+			embedded, err := structFields(ft, parent, visiting)
+			if err != nil {
+				return nil, err
+			}
+			for k, f := range embedded {
+				if _, ok := fields[k]; !ok {
+					fields[k] = f
+				}
+			}
+			continue
+		}
+
+		f, err := field(sf, parent, visiting)
+		if err != nil {
+			return nil, err
+		}
+		fields[key] = f
+	}
+
+	return fields, nil
+}
+
+// jsonKey returns the name of the struct field used by encoding/json.
+func jsonKey(sf reflect.StructField) (key string, skip bool) {
+	tag := sf.Tag.Get(tagJSON)
+	if tag == "-" {
+		return "", true
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = sf.Name
+	}
+	return name, false
+}
+
+func field(sf reflect.StructField, parent string, visiting map[reflect.Type]bool) (*Field, error) {
+	name := sf.Tag.Get(tagName)
+	if name == "" {
+		name = sf.Name
+	}
+	if parent != "" {
+		name = parent + "." + name
+	}
+
+	f := &Field{
+		Name:        name,
+		Description: sf.Tag.Get(tagDescription),
+		Nullable:    sf.Type.Kind() == reflect.Ptr,
+	}
+
+	err := describe(f, sf.Type, sf.Tag.Get(tagFormat), visiting)
+	if err != nil {
+		return nil, fmt.Errorf("schema: field %s: %v", sf.Name, err)
+	}
+
+	err = constrain(f, sf)
+	if err != nil {
+		return nil, fmt.Errorf("schema: field %s: %v", sf.Name, err)
+	}
+	return f, nil
+}
+
+// describe sets the Type, the Format and the nested Fields or Items of f according to the Go type.
+func describe(f *Field, t reflect.Type, format string, visiting map[reflect.Type]bool) error {
+	t = indirect(t)
+
+	switch {
+	case t == timeType && format == formatDate:
+		f.Type = Date
+		return nil
+	case t == timeType:
+		f.Type = Datetime
+		return nil
+	case t == durationType:
+		f.Type = Integer
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		f.Type = Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		f.Type = Integer
+	case reflect.Float32, reflect.Float64:
+		f.Type = Float
+	case reflect.String:
+		f.Type = String
+		switch format {
+		case "":
+		case formatDate:
+			f.Type = Date
+		case formatDuration:
+			f.Format = FormatDuration
+		default:
+			f.Format = Format(format)
+		}
+	case reflect.Struct:
+		f.Type = Object
+		fields, err := structFields(t, f.Name, visiting)
+		if err != nil {
+			return err
+		}
+		f.Fields = fields
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported map key %s", t.Key())
+		}
+		f.Type = Object
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is encoded as a base64 string.
+			f.Type = String
+			return nil
+		}
+		f.Type = Collection
+		f.Items = &Field{Name: f.Name, Nullable: t.Elem().Kind() == reflect.Ptr}
+		err := describe(f.Items, t.Elem(), format, visiting)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", t)
+	}
+	return nil
+}
+
+// constrain sets the constraints of f according to the struct tags.
+func constrain(f *Field, sf reflect.StructField) (err error) {
+	if v, ok := sf.Tag.Lookup(tagRequired); ok {
+		f.Required, err = strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagNullable); ok {
+		f.Nullable, err = strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagSecret); ok {
+		f.Secret, err = strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagPattern); ok {
+		_, err = regexp.Compile(v)
+		if err != nil {
+			return err
+		}
+		f.Pattern = v
+	}
+
+	if v, ok := sf.Tag.Lookup(tagDefault); ok {
+		f.Default, err = parseValue(f.Type, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagEnum); ok {
+		f.Enum, err = parseList(f.Type, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagExamples); ok {
+		f.Examples, err = parseList(f.Type, v)
+		if err != nil {
+			return err
+		}
+	}
+
+	if v, ok := sf.Tag.Lookup(tagMin); ok {
+		min, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		f.Min = &min
+	}
+
+	if v, ok := sf.Tag.Lookup(tagMax); ok {
+		max, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return err
+		}
+		f.Max = &max
+	}
+
+	return nil
+}
+
+// parseList parses a comma-separated tag value according to the field type.
+func parseList(t FieldType, v string) ([]interface{}, error) {
+	var res []interface{}
+	for _, s := range strings.Split(v, listSeparator) {
+		e, err := parseValue(t, strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// parseValue parses a tag value according to the field type.
+func parseValue(t FieldType, v string) (interface{}, error) {
+	switch t {
+	case Boolean:
+		return strconv.ParseBool(v)
+	case Integer:
+		return strconv.ParseInt(v, 10, 64)
+	case Float:
+		return strconv.ParseFloat(v, 64)
+	case String, Date, Datetime:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s cannot have a default, enum or example value", t.Name)
+}
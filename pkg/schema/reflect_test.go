@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Base struct {
+	ID   string `json:"id" required:"true"`
+	Name string `json:"name"`
+}
+
+type reflectConfig struct {
+	Base
+	Name       string `json:"title" name:"Title" desc:"Overrides the embedded name"`
+	Skipped    string `json:"-"`
+	unexported string
+	Untagged   bool
+	Data       []byte            `json:"data"`
+	Labels     map[string]string `json:"labels"`
+	Timeout    time.Duration     `json:"timeout" default:"1000"`
+	Every      string            `json:"every" format:"duration"`
+	Day        time.Time         `json:"day" format:"date"`
+	At         *time.Time        `json:"at"`
+	Level      string            `json:"level" enum:"debug, info" default:"info"`
+	Ratio      float64           `json:"ratio" min:"0" max:"1" examples:"0.5"`
+	Retry      *int              `json:"retry" nullable:"false"`
+	Token      string            `json:"token" secret:"true" pattern:"^[a-z]+$"`
+	Owner      struct {
+		Email string `json:"email" format:"email"`
+	} `json:"owner"`
+	Hosts []*struct {
+		URL string `json:"url" format:"url"`
+	} `json:"hosts"`
+}
+
+func TestFieldsOf(t *testing.T) {
+	f, err := FieldsOf(&reflectConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	for _, k := range []string{"-", "Skipped", "unexported", "Base"} {
+		if _, ok := f[k]; ok {
+			t.Errorf("got the field %s in %v", k, keys)
+		}
+	}
+
+	zero, one := 0.0, 1.0
+	tests := []struct {
+		key  string
+		want Field
+	}{
+		{"id", Field{Name: "ID", Type: String, Required: true}},
+		{"name", Field{Name: "Name", Type: String}},
+		{"title", Field{Name: "Title", Type: String, Description: "Overrides the embedded name"}},
+		{"Untagged", Field{Name: "Untagged", Type: Boolean}},
+		{"data", Field{Name: "Data", Type: String}},
+		{"labels", Field{Name: "Labels", Type: Object}},
+		{"timeout", Field{Name: "Timeout", Type: Integer, Default: int64(1000)}},
+		{"every", Field{Name: "Every", Type: String, Format: FormatDuration}},
+		{"day", Field{Name: "Day", Type: Date}},
+		{"at", Field{Name: "At", Type: Datetime, Nullable: true}},
+		{"level", Field{Name: "Level", Type: String, Enum: []interface{}{"debug", "info"}, Default: "info"}},
+		{"ratio", Field{Name: "Ratio", Type: Float, Min: &zero, Max: &one, Examples: []interface{}{0.5}}},
+		{"retry", Field{Name: "Retry", Type: Integer}},
+		{"token", Field{Name: "Token", Type: String, Secret: true, Pattern: "^[a-z]+$"}},
+		{"owner", Field{Name: "Owner", Type: Object, Fields: Fields{
+			"email": {Name: "Owner.Email", Type: String, Format: FormatEmail},
+		}}},
+		{"hosts", Field{Name: "Hosts", Type: Collection, Items: &Field{
+			Name:     "Hosts",
+			Type:     Object,
+			Nullable: true,
+			Fields:   Fields{"url": {Name: "Hosts.URL", Type: String, Format: FormatURL}},
+		}}},
+	}
+	for _, tt := range tests {
+		got, ok := f[tt.key]
+		if !ok {
+			t.Errorf("%s is missing in %v", tt.key, keys)
+			continue
+		}
+		if !reflect.DeepEqual(*got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.key, *got, tt.want)
+		}
+	}
+	if len(f) != len(tests) {
+		t.Errorf("got the fields %v, want %d fields", keys, len(tests))
+	}
+}
+
+type recursive struct {
+	Children []recursive `json:"children"`
+}
+
+type recursivePtr struct {
+	Parent *recursivePtr `json:"parent"`
+}
+
+type siblings struct {
+	A Base `json:"a"`
+	B Base `json:"b"`
+}
+
+func TestFieldsOfErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config interface{}
+		err    string
+	}{
+		{"not a struct", "config", "is not a struct"},
+		{"recursive slice", recursive{}, "recursive type"},
+		{"recursive pointer", &recursivePtr{}, "recursive type"},
+		{"map key", struct {
+			M map[int]string `json:"m"`
+		}{}, "unsupported map key int"},
+		{"channel", struct {
+			C chan int `json:"c"`
+		}{}, "unsupported type chan int"},
+		{"required", struct {
+			S string `required:"yes"`
+		}{}, "field S"},
+		{"default", struct {
+			N int `default:"ten"`
+		}{}, "field N"},
+		{"enum", struct {
+			B bool `enum:"true,maybe"`
+		}{}, "field B"},
+		{"min", struct {
+			N int `min:"one"`
+		}{}, "field N"},
+		{"pattern", struct {
+			S string `pattern:"["`
+		}{}, "field S"},
+		{"default of an object", struct {
+			O struct{} `default:"{}"`
+		}{}, "object cannot have a default"},
+	}
+	for _, tt := range tests {
+		_, err := FieldsOf(tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.err)
+		}
+	}
+
+	// The same struct type may be used twice, only recursion is an error.
+	f, err := FieldsOf(siblings{})
+	if err != nil || len(f["a"].Fields) != 2 || len(f["b"].Fields) != 2 {
+		t.Errorf("got %v, %v", f, err)
+	}
+
+	f, err = FieldsOf(nil)
+	if f != nil || err != nil {
+		t.Errorf("got %v, %v for a nil config", f, err)
+	}
+}
@@ -0,0 +1,303 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Layouts of Date and Datetime values.
+const (
+	DateLayout     = "2006-01-02"
+	DatetimeLayout = time.RFC3339
+)
+
+// ValidationError describes a value that does not match its Field.
+// Path addresses the value, e.g. "file.name" or "items[2].name".
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors is a list of ValidationError sorted by Path.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Prefix returns the errors with the paths prefixed, e.g. with the path of the parent object.
+func (e ValidationErrors) Prefix(prefix string) ValidationErrors {
+	res := make(ValidationErrors, len(e))
+	for i, err := range e {
+		res[i] = ValidationError{Path: joinPath(prefix, err.Path), Message: err.Message}
+	}
+	return res
+}
+
+// Validate checks the decoded JSON object, e.g. a message or a config, against the fields.
+// It returns ValidationErrors if the object does not match the fields and nil otherwise.
+// Fields that are not described are allowed. Absent and null values are allowed
+// unless the field is required. Required fields may be null if they are nullable.
+func Validate(f Fields, v map[string]interface{}) error {
+	var errs ValidationErrors
+	validateObject(f, v, "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].Path < errs[j].Path })
+	return errs
+}
+
+// ValidateJSON is like Validate but decodes the JSON object first.
+// Empty data is validated as an empty object.
+func ValidateJSON(f Fields, data []byte) error {
+	v := make(map[string]interface{})
+	if len(data) > 0 {
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			return ValidationErrors{{Message: "invalid JSON object: " + err.Error()}}
+		}
+	}
+	return Validate(f, v)
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	if key == "" || strings.HasPrefix(key, "[") {
+		return parent + key
+	}
+	return parent + "." + key
+}
+
+func validateObject(f Fields, v map[string]interface{}, path string, errs *ValidationErrors) {
+	for key, field := range f {
+		p := joinPath(path, key)
+		value, ok := v[key]
+		if !ok || value == nil {
+			if field.Required && (!ok || !field.Nullable) {
+				*errs = append(*errs, ValidationError{Path: p, Message: "is required"})
+			}
+			continue
+		}
+		validateValue(field, value, p, errs)
+	}
+}
+
+func validateValue(f *Field, v interface{}, path string, errs *ValidationErrors) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch f.Type {
+	case Boolean:
+		if _, ok := v.(bool); !ok {
+			fail("must be a boolean")
+			return
+		}
+	case Integer:
+		n, ok := number(v)
+		if !ok || n != float64(int64(n)) {
+			fail("must be an integer")
+			return
+		}
+		limit(f, n, "", fail)
+	case Float:
+		n, ok := number(v)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		limit(f, n, "", fail)
+	case String:
+		s, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		limit(f, float64(utf8.RuneCountInString(s)), "length ", fail)
+		validateString(f, s, fail)
+	case Date, Datetime:
+		if _, ok := v.(time.Time); ok {
+			break
+		}
+		layout := DatetimeLayout
+		if f.Type == Date {
+			layout = DateLayout
+		}
+		s, ok := v.(string)
+		if !ok {
+			fail("must be a %s string", f.Type.Name)
+			return
+		}
+		if _, err := time.Parse(layout, s); err != nil {
+			fail("must be a %s in the %s format", f.Type.Name, layout)
+			return
+		}
+	case Object:
+		m, ok := object(v)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		validateObject(f.Fields, m, path, errs)
+	case Collection:
+		items, ok := collection(v)
+		if !ok {
+			fail("must be a collection")
+			return
+		}
+		limit(f, float64(len(items)), "length ", fail)
+		elem := f.Elem()
+		if elem == nil {
+			break
+		}
+		for i, item := range items {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if item == nil {
+				if !elem.Nullable {
+					*errs = append(*errs, ValidationError{Path: p, Message: "must not be null"})
+				}
+				continue
+			}
+			validateValue(elem, item, p, errs)
+		}
+	}
+
+	if len(f.Enum) > 0 && !oneOf(v, f.Enum) {
+		fail("must be one of %v", f.Enum)
+	}
+}
+
+// validateString checks the Format and the Pattern of the string.
+func validateString(f *Field, s string, fail func(format string, args ...interface{})) {
+	switch f.Format {
+	case FormatEmail:
+		if a, err := mail.ParseAddress(s); err != nil || a.Address != s {
+			fail("must be an email address")
+		}
+	case FormatURL:
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("must be an absolute URL")
+		}
+	case FormatDuration:
+		if _, err := time.ParseDuration(s); err != nil {
+			fail("must be a duration, e.g. 1h30m")
+		}
+	}
+
+	if f.Pattern == "" {
+		return
+	}
+	re, err := regexp.Compile(f.Pattern)
+	if err != nil {
+		fail("has an invalid pattern %q: %v", f.Pattern, err)
+		return
+	}
+	if !re.MatchString(s) {
+		fail("must match %s", f.Pattern)
+	}
+}
+
+func limit(f *Field, n float64, what string, fail func(format string, args ...interface{})) {
+	if f.Min != nil && n < *f.Min {
+		fail("%smust be at least %v", what, *f.Min)
+	}
+	if f.Max != nil && n > *f.Max {
+		fail("%smust be at most %v", what, *f.Max)
+	}
+}
+
+func oneOf(v interface{}, enum []interface{}) bool {
+	n, isNumber := number(v)
+	for _, e := range enum {
+		if isNumber {
+			if en, ok := number(e); ok && en == n {
+				return true
+			}
+			continue
+		}
+		// Lists and objects are not comparable with ==.
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// number converts the numeric value to float64.
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// object converts any map with string keys, e.g. satellite.Message, to map[string]interface{}.
+func object(v interface{}) (map[string]interface{}, bool) {
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	for _, k := range rv.MapKeys() {
+		m[k.String()] = rv.MapIndex(k).Interface()
+	}
+	return m, true
+}
+
+// collection converts any slice or array to []interface{}.
+func collection(v interface{}) ([]interface{}, bool) {
+	if items, ok := v.([]interface{}); ok {
+		return items, true
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
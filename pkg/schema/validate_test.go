@@ -0,0 +1,37 @@
+package schema
+
+import "testing"
+
+func TestValidateEnum(t *testing.T) {
+	f := Fields{
+		"status": {Name: "Status", Type: String, Enum: []interface{}{"Open", "Closed"}},
+		"points": {Name: "Points", Type: Integer, Enum: []interface{}{int64(1), int64(2)}},
+		"labels": {Name: "Labels", Type: Collection, Enum: []interface{}{[]interface{}{"a"}}},
+		"owner": {Name: "Owner", Type: Object, Enum: []interface{}{
+			map[string]interface{}{"login": "root"},
+		}},
+	}
+
+	valid := []map[string]interface{}{
+		{"status": "Open", "points": 2.0},
+		{"labels": []interface{}{"a"}},
+		{"owner": map[string]interface{}{"login": "root"}},
+	}
+	for _, v := range valid {
+		if err := Validate(f, v); err != nil {
+			t.Errorf("%v: %v", v, err)
+		}
+	}
+
+	invalid := []map[string]interface{}{
+		{"status": "Resolved"},
+		{"points": 3.0},
+		{"labels": []interface{}{"b"}},
+		{"owner": map[string]interface{}{"login": "guest"}},
+	}
+	for _, v := range invalid {
+		if err := Validate(f, v); err == nil {
+			t.Errorf("%v: no error", v)
+		}
+	}
+}
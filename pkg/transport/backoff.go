@@ -0,0 +1,62 @@
+package transport
+
+import (
+	"context"
+	"time"
+)
+
+// Backoff calculates exponentially growing delays between retries.
+// The zero value is not usable, use NewBackoff instead.
+type Backoff struct {
+	min     time.Duration
+	max     time.Duration
+	attempt uint
+}
+
+// NewBackoff creates a Backoff that starts with min and doubles the delay
+// on each attempt until it reaches max.
+func NewBackoff(min, max time.Duration) *Backoff {
+	if max < min {
+		max = min
+	}
+	return &Backoff{min: min, max: max}
+}
+
+// Next returns the delay for the current attempt and moves to the next one.
+func (b *Backoff) Next() time.Duration {
+	shift := b.attempt
+	if shift > 32 {
+		shift = 32
+	}
+	b.attempt++
+
+	d := b.min << shift
+	if d > b.max || d < b.min {
+		d = b.max
+	}
+	return d
+}
+
+// Attempt returns the number of delays handed out since the last Reset.
+func (b *Backoff) Attempt() uint {
+	return b.attempt
+}
+
+// Reset starts over from the min delay.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
+
+// Wait sleeps for the next delay. It returns the context's error if
+// the context is done before the delay expires.
+func (b *Backoff) Wait(ctx context.Context) error {
+	t := time.NewTimer(b.Next())
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,67 @@
+package transport
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(100*time.Millisecond, time.Second)
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if d := b.Next(); d != w {
+			t.Errorf("attempt %d: got %v, want %v", i, d, w)
+		}
+	}
+	if b.Attempt() != uint(len(want)) {
+		t.Errorf("got %d attempts, want %d", b.Attempt(), len(want))
+	}
+
+	b.Reset()
+	if b.Attempt() != 0 {
+		t.Errorf("got %d attempts after Reset", b.Attempt())
+	}
+	if d := b.Next(); d != 100*time.Millisecond {
+		t.Errorf("got %v after Reset, want 100ms", d)
+	}
+}
+
+func TestBackoffOverflow(t *testing.T) {
+	b := NewBackoff(time.Second, time.Hour)
+	for i := 0; i < 100; i++ {
+		if d := b.Next(); d < time.Second || d > time.Hour {
+			t.Fatalf("attempt %d: got %v", i, d)
+		}
+	}
+}
+
+func TestBackoffMaxBelowMin(t *testing.T) {
+	b := NewBackoff(time.Second, time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if d := b.Next(); d != time.Second {
+			t.Errorf("attempt %d: got %v, want 1s", i, d)
+		}
+	}
+}
+
+func TestBackoffWait(t *testing.T) {
+	b := NewBackoff(time.Millisecond, time.Millisecond)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Errorf("got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b = NewBackoff(time.Hour, time.Hour)
+	if err := b.Wait(ctx); err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+}
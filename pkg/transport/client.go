@@ -18,6 +18,9 @@ type Client struct {
 	dec            DecodeResponseFunc
 	before         []ClientRequestFunc
 	after          []ClientResponseFunc
+	retries        int
+	minBackoff     time.Duration
+	maxBackoff     time.Duration
 }
 
 // NewClient constructs a usable Client for a single remote method.
@@ -35,6 +38,9 @@ func NewClient(
 		receiveTimeout: receiveTimeout,
 		enc:            enc,
 		dec:            dec,
+		retries:        3,
+		minBackoff:     100 * time.Millisecond,
+		maxBackoff:     time.Second,
 	}
 	for _, option := range options {
 		option(c)
@@ -54,6 +60,12 @@ type ClientResponseFunc func(ctx context.Context, res interface{}) context.Conte
 // ClientOption sets an optional parameter for clients.
 type ClientOption func(*Client)
 
+// ClientNamespace prefixes the topic and the reply topics with the namespace,
+// so that clients of different tenants sharing a message broker do not collide.
+func ClientNamespace(namespace string) ClientOption {
+	return func(c *Client) { c.topic = NamespacedTopic(namespace, c.topic) }
+}
+
 // SetConnection sets the underlying redis.Connection used for requests.
 func SetConnection(conn Connection) ClientOption {
 	return func(c *Client) { c.conn = conn }
@@ -72,6 +84,19 @@ func ClientAfter(after ...ClientResponseFunc) ClientOption {
 	return func(c *Client) { c.after = append(c.after, after...) }
 }
 
+// ClientRetry sets how many times a failed Send or Receive is retried, e.g. while
+// the connection to the message broker is being reestablished. The delay between
+// the retries starts from min and doubles on each retry until it reaches max.
+// Timeouts are not retried. Zero retries disable retrying.
+// By default, there are 3 retries with the delay growing from 100ms up to 1s.
+func ClientRetry(retries int, min, max time.Duration) ClientOption {
+	return func(c *Client) {
+		c.retries = retries
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
 // Endpoint returns a usable endpoint that invokes the remote endpoint.
 func (c *Client) Endpoint() endpoint.Endpoint {
 	return func(ctx context.Context, req interface{}) (res interface{}, err error) {
@@ -92,12 +117,18 @@ func (c *Client) Endpoint() endpoint.Endpoint {
 			return nil, err
 		}
 
-		err = c.conn.Send(c.topic, replyTopic, request)
+		err = c.retry(ctx, func() error {
+			return c.conn.Send(c.topic, replyTopic, request)
+		})
 		if err != nil {
 			return nil, err
 		}
 
-		_, response, err := c.conn.Receive(replyTopic, c.receiveTimeout)
+		var response interface{}
+		err = c.retry(ctx, func() (err error) {
+			_, response, err = c.conn.Receive(replyTopic, c.receiveTimeout)
+			return err
+		})
 		if err != nil {
 			return nil, err
 		}
@@ -115,6 +146,23 @@ func (c *Client) Endpoint() endpoint.Endpoint {
 	}
 }
 
+// retry calls f until it succeeds, times out or the retries are exhausted,
+// and returns the error of the last call. It returns the context's error
+// if the context is done while waiting for a retry.
+func (c *Client) retry(ctx context.Context, f func() error) error {
+	b := NewBackoff(c.minBackoff, c.maxBackoff)
+	for {
+		err := f()
+		if err == nil || err == ErrTimeout || b.Attempt() >= uint(c.retries) {
+			return err
+		}
+
+		if err := b.Wait(ctx); err != nil {
+			return err
+		}
+	}
+}
+
 func createReplyTopic(topic string) (replyTopic string, err error) {
 	t := time.Now()
 	entropy := rand.New(rand.NewSource(t.UnixNano()))
@@ -123,5 +171,5 @@ func createReplyTopic(topic string) (replyTopic string, err error) {
 		return "", err
 	}
 
-	return topic + ":reply:" + id.String(), nil
+	return topic + replySeparator + id.String(), nil
 }
@@ -0,0 +1,119 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errBroken = errors.New("broken pipe")
+
+// flakyConn fails the first calls of Send and Receive, like a connection
+// to a message broker that is being restarted.
+type flakyConn struct {
+	*memConn
+
+	mu           sync.Mutex
+	sendFails    int
+	receiveFails int
+	sends        int
+}
+
+func (f *flakyConn) Send(topic, replyTopic string, data interface{}) error {
+	f.mu.Lock()
+	f.sends++
+	fail := f.sendFails > 0
+	if fail {
+		f.sendFails--
+	}
+	f.mu.Unlock()
+
+	if fail {
+		return errBroken
+	}
+	return f.memConn.Send(topic, replyTopic, data)
+}
+
+func (f *flakyConn) Receive(topic string, timeout time.Duration) (string, interface{}, error) {
+	f.mu.Lock()
+	fail := f.receiveFails > 0
+	if fail {
+		f.receiveFails--
+	}
+	f.mu.Unlock()
+
+	if fail {
+		return "", nil, errBroken
+	}
+	return f.memConn.Receive(topic, timeout)
+}
+
+// echo replies to a single request received from the topic.
+func echo(conn Connection, topic string) {
+	replyTopic, data, err := conn.Receive(topic, time.Second)
+	if err == nil {
+		_ = conn.Send(replyTopic, NoReply, data)
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	conn := &flakyConn{memConn: newMemConn(), sendFails: 2, receiveFails: 2}
+	c := NewClient(conn, "echo", time.Second, passthrough, decodeString,
+		ClientRetry(2, time.Millisecond, time.Millisecond))
+	go echo(conn.memConn, "echo")
+
+	res, err := c.Endpoint()(context.Background(), []byte("hello"))
+	if err != nil || res != "hello" {
+		t.Errorf("got %v, %v", res, err)
+	}
+	if conn.sends != 3 {
+		t.Errorf("got %d sends, want 3", conn.sends)
+	}
+}
+
+func TestClientRetryExhausted(t *testing.T) {
+	conn := &flakyConn{memConn: newMemConn(), sendFails: 3}
+	c := NewClient(conn, "echo", time.Second, passthrough, decodeString,
+		ClientRetry(2, time.Millisecond, time.Millisecond))
+
+	if res, err := c.Endpoint()(context.Background(), []byte("hello")); err != errBroken {
+		t.Errorf("got %v, %v, want errBroken", res, err)
+	}
+	if conn.sends != 3 {
+		t.Errorf("got %d sends, want 3", conn.sends)
+	}
+
+	conn = &flakyConn{memConn: newMemConn(), sendFails: 1}
+	c = NewClient(conn, "echo", time.Second, passthrough, decodeString, ClientRetry(0, 0, 0))
+	if _, err := c.Endpoint()(context.Background(), []byte("hello")); err != errBroken || conn.sends != 1 {
+		t.Errorf("got %v after %d sends without retries", err, conn.sends)
+	}
+}
+
+func TestClientRetryCanceled(t *testing.T) {
+	conn := &flakyConn{memConn: newMemConn(), sendFails: 1}
+	c := NewClient(conn, "echo", time.Second, passthrough, decodeString,
+		ClientRetry(1, time.Hour, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if res, err := c.Endpoint()(ctx, []byte("hello")); err != context.DeadlineExceeded {
+		t.Errorf("got %v, %v, want context.DeadlineExceeded", res, err)
+	}
+}
+
+func TestClientTimeoutIsNotRetried(t *testing.T) {
+	conn := newMemConn()
+	c := NewClient(conn, "echo", 20*time.Millisecond, passthrough, decodeString,
+		ClientRetry(3, time.Hour, time.Hour))
+
+	start := time.Now()
+	if res, err := c.Endpoint()(context.Background(), []byte("hello")); err != ErrTimeout {
+		t.Errorf("got %v, %v, want ErrTimeout", res, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("the timeout has been retried for %v", d)
+	}
+}
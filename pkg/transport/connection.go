@@ -7,12 +7,27 @@ import (
 
 const NoReply = ""
 
+// NamespaceSeparator separates a namespace from a topic.
+const NamespaceSeparator = ":"
+
+// NamespacedTopic prefixes the topic with the namespace.
+// An empty namespace leaves the topic untouched.
+func NamespacedTopic(namespace, topic string) string {
+	if namespace == "" {
+		return topic
+	}
+	return namespace + NamespaceSeparator + topic
+}
+
 // ErrInvalidResponse indicates corrupted response from a message broker.
 var ErrInvalidResponse = errors.New("invalid response")
 
 // ErrTimeout is returned when Send or Receive timeouts.
 var ErrTimeout = errors.New("timeout is reached")
 
+// ErrUnhealthy is returned when a message broker does not respond to a health check.
+var ErrUnhealthy = errors.New("connection is unhealthy")
+
 // Connection is a RPC over a message broker.
 type Connection interface {
 	// Send sends data to the topic.
@@ -20,4 +35,30 @@ type Connection interface {
 
 	// Receive receives data from the topic.
 	Receive(topic string, timeout time.Duration) (replyTopic string, data interface{}, err error)
+
+	// Ping checks that the message broker is reachable.
+	Ping() error
+}
+
+// Leaser is implemented by connections, the message broker of which can grant leases,
+// e.g. so that only one of many satellite instances does the same thing.
+type Leaser interface {
+	// Lease acquires the named lease for the duration. It reports false if the lease
+	// has been acquired by anyone, including the caller, and has not expired yet.
+	Lease(name string, ttl time.Duration) (bool, error)
+}
+
+// Requeuer is implemented by connections, the message broker of which can put a message back
+// in front of the topic, e.g. so that a request the server could not handle is received first again.
+type Requeuer interface {
+	// Requeue sends the message to the topic, it is received before the messages already in the topic.
+	Requeue(topic, replyTopic string, data interface{}) error
+}
+
+// Expirer is implemented by connections, the message broker of which can expire topics,
+// e.g. so that the reply topics of abandoned streams do not pile up.
+type Expirer interface {
+	// Expire deletes the topic with the data it holds after the duration.
+	// A zero duration deletes the topic at once.
+	Expire(topic string, ttl time.Duration) error
 }
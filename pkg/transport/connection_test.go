@@ -0,0 +1,20 @@
+package transport
+
+import "testing"
+
+func TestNamespacedTopic(t *testing.T) {
+	tests := []struct {
+		namespace string
+		topic     string
+		want      string
+	}{
+		{"", "satellite.register", "satellite.register"},
+		{"acme", "satellite.register", "acme:satellite.register"},
+		{"acme", "mission.*.step.>", "acme:mission.*.step.>"},
+	}
+	for _, tt := range tests {
+		if got := NamespacedTopic(tt.namespace, tt.topic); got != tt.want {
+			t.Errorf("NamespacedTopic(%q, %q) = %q, want %q", tt.namespace, tt.topic, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package redis
 import (
 	"encoding/base64"
 	"encoding/json"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/antonkuzmenko/gogarin/pkg/transport"
@@ -11,22 +13,56 @@ import (
 
 // New creates a connection pool that implements transport.Connection.
 func New(c Config) transport.Connection {
-	pool := &redis.Pool{
+	return &Connection{
+		pool:              NewPool(c),
+		discoveryInterval: time.Duration(c.PatternDiscoveryIntervalInMs) * time.Millisecond,
+		discovered:        make(map[string]discovery),
+	}
+}
+
+// NewPool creates a connection pool configured like the pool of a Connection,
+// e.g. for the satellites storing their data in redis.
+func NewPool(c Config) *redis.Pool {
+	return &redis.Pool{
 		MaxActive:   c.MaxActiveConnections,
 		MaxIdle:     c.MaxIdleConnections,
 		IdleTimeout: time.Duration(c.ConnectionIdleTimeoutInMs) * time.Millisecond,
 		Dial: func() (redis.Conn, error) {
-			return redis.Dial(
-				"tcp",
-				c.Address,
-				redis.DialConnectTimeout(time.Duration(c.ConnectTimeoutInMs)*time.Millisecond),
-				redis.DialReadTimeout(time.Duration(c.ReadTimeoutInMs)*time.Millisecond),
-				redis.DialWriteTimeout(time.Duration(c.WriteTimeoutInMs)*time.Millisecond),
-			)
+			return dial(c)
+		},
+		TestOnBorrow: func(con redis.Conn, t time.Time) error {
+			if time.Since(t) < time.Duration(c.TestOnBorrowAfterInMs)*time.Millisecond {
+				return nil
+			}
+			return ping(con)
 		},
 	}
+}
+
+// dial connects to the redis server once. It does not retry, so that no caller is blocked
+// beyond ConnectTimeoutInMs. When the server is unreachable, e.g. it is being restarted,
+// the callers retry with a backoff of their own: transport.Client retries Send and Receive,
+// and transport.Server backs off its Receive loop. Every retry borrows a connection, which redials.
+func dial(c Config) (redis.Conn, error) {
+	return redis.Dial(
+		"tcp",
+		c.Address,
+		redis.DialConnectTimeout(time.Duration(c.ConnectTimeoutInMs)*time.Millisecond),
+		redis.DialReadTimeout(time.Duration(c.ReadTimeoutInMs)*time.Millisecond),
+		redis.DialWriteTimeout(time.Duration(c.WriteTimeoutInMs)*time.Millisecond),
+		redis.DialDatabase(c.DB),
+	)
+}
 
-	return &Connection{pool: pool}
+func ping(con redis.Conn) error {
+	res, err := redis.String(con.Do("PING"))
+	if err != nil {
+		return err
+	}
+	if res != "PONG" {
+		return transport.ErrUnhealthy
+	}
+	return nil
 }
 
 // Config for redis.Pool.
@@ -61,6 +97,19 @@ type Config struct {
 	// WriteTimeoutInMs specifies the timeout for writing a single command.
 	// The default WriteTimeoutInMs is 10000ms/10s.
 	WriteTimeoutInMs int `default:"10000"`
+
+	// TestOnBorrowAfterInMs pings idle connections taken from the pool when they
+	// have been idle for longer than this duration. Stale connections, e.g. after
+	// the redis server restart, are closed and replaced with new ones.
+	// If the value is zero, every borrowed connection is checked.
+	// The default TestOnBorrowAfterInMs is 0ms, so that no stale connection is handed out.
+	TestOnBorrowAfterInMs int `default:"0"`
+
+	// PatternDiscoveryIntervalInMs specifies how often the keys matching a pattern
+	// topic are looked up. Redis has no wildcard BRPOP, so pattern topics are
+	// emulated by discovering the existing keys with SCAN.
+	// The default PatternDiscoveryIntervalInMs is 1000ms/1s.
+	PatternDiscoveryIntervalInMs int `default:"1000"`
 }
 
 type message struct {
@@ -69,12 +118,41 @@ type message struct {
 }
 
 type Connection struct {
-	pool *redis.Pool
+	pool              *redis.Pool
+	discoveryInterval time.Duration
+
+	mu         sync.Mutex
+	discovered map[string]discovery
+}
+
+// discovery is the result of the latest lookup of the keys matching a pattern.
+type discovery struct {
+	topics []string
+	at     time.Time
+}
+
+// Ping checks that the redis server is reachable.
+func (r *Connection) Ping() error {
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	if con.Err() != nil {
+		return con.Err()
+	}
+
+	return ping(con)
 }
 
 func (r *Connection) Send(topic, replyTopic string, data interface{}) (err error) {
-	const command = "LPUSH"
+	return r.push("LPUSH", topic, replyTopic, data)
+}
 
+// Requeue pushes the message to the end of the list popped by Receive,
+// so that it is received before the messages sent with Send.
+func (r *Connection) Requeue(topic, replyTopic string, data interface{}) error {
+	return r.push("RPUSH", topic, replyTopic, data)
+}
+
+func (r *Connection) push(command, topic, replyTopic string, data interface{}) error {
 	con := r.pool.Get()
 	defer con.Close() // nolint: errcheck
 	if con.Err() != nil {
@@ -100,33 +178,248 @@ func (r *Connection) Receive(topic string, timeout time.Duration) (replyTopic st
 		return "", nil, con.Err()
 	}
 
-	res, err := con.Do(command, topic, int(timeout.Seconds()))
+	res, err := con.Do(command, topic, blockTimeout(timeout))
 	if err != nil {
 		return "", nil, err
 	}
 
-	result, err := redis.ByteSlices(res, err)
+	_, replyTopic, data, err = decode(res)
+	return replyTopic, data, err
+}
+
+// blockTimeout converts the timeout to the seconds of a blocking command.
+// Redis blocks forever on a zero timeout, so it is rounded up to a whole second.
+func blockTimeout(timeout time.Duration) int {
+	s := int((timeout + time.Second - 1) / time.Second)
+	if s < 1 {
+		return 1
+	}
+	return s
+}
+
+// Lease implements transport.Leaser. The lease is a key that expires after the duration.
+func (r *Connection) Lease(name string, ttl time.Duration) (bool, error) {
+	const command = "SET"
+
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	if con.Err() != nil {
+		return false, con.Err()
+	}
+
+	ms := int64(ttl / time.Millisecond)
+	if ms < 1 {
+		ms = 1
+	}
+	_, err := redis.String(con.Do(command, name, time.Now().UTC().Format(time.RFC3339Nano), "NX", "PX", ms))
 	if err == redis.ErrNil {
-		return "", nil, transport.ErrTimeout
+		return false, nil
 	}
 	if err != nil {
-		return "", nil, err
+		return false, err
+	}
+	return true, nil
+}
+
+// Expire implements transport.Expirer.
+func (r *Connection) Expire(topic string, ttl time.Duration) error {
+	const command = "PEXPIRE"
+
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	if con.Err() != nil {
+		return con.Err()
+	}
+
+	_, err := con.Do(command, topic, int64(ttl/time.Millisecond))
+	return err
+}
+
+// ReceivePattern implements transport.PatternReceiver.
+// It receives data from the existing lists matching the pattern, except the listed ones.
+func (r *Connection) ReceivePattern(pattern string, except []string, timeout time.Duration) (
+	topic, replyTopic string, data interface{}, err error,
+) {
+	const command = "BRPOP"
+
+	topics, err := r.discover(pattern, except)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if len(topics) == 0 {
+		// Nothing to receive from yet, wait for the topics to appear.
+		wait := r.discoveryInterval
+		if timeout < wait {
+			wait = timeout
+		}
+		time.Sleep(wait)
+		return "", "", nil, transport.ErrTimeout
+	}
+
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	if con.Err() != nil {
+		return "", "", nil, con.Err()
+	}
+
+	args := make([]interface{}, 0, len(topics)+1)
+	for _, t := range topics {
+		args = append(args, t)
+	}
+	args = append(args, blockTimeout(timeout))
+
+	res, err := con.Do(command, args...)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return decode(res)
+}
+
+// discover returns the keys matching the pattern. The keys are looked up
+// at most once per the discovery interval.
+func (r *Connection) discover(pattern string, except []string) ([]string, error) {
+	r.mu.Lock()
+	d, ok := r.discovered[pattern]
+	r.mu.Unlock()
+
+	if !ok || time.Since(d.at) >= r.discoveryInterval {
+		topics, err := r.scan(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		d = discovery{topics: topics, at: time.Now()}
+		r.mu.Lock()
+		r.discovered[pattern] = d
+		r.mu.Unlock()
+	}
+
+	skip := make(map[string]bool, len(except))
+	for _, t := range except {
+		skip[t] = true
+	}
+
+	var topics []string
+	for _, t := range d.topics {
+		if !skip[t] {
+			topics = append(topics, t)
+		}
+	}
+	return topics, nil
+}
+
+func (r *Connection) scan(pattern string) ([]string, error) {
+	const command = "SCAN"
+
+	con := r.pool.Get()
+	defer con.Close() // nolint: errcheck
+	if con.Err() != nil {
+		return nil, con.Err()
+	}
+
+	var topics []string
+	cursor := 0
+	for {
+		res, err := redis.Values(con.Do(command, cursor, "MATCH", glob(pattern), "COUNT", 1000))
+		if err != nil {
+			return nil, err
+		}
+		if len(res) != 2 {
+			return nil, transport.ErrInvalidResponse
+		}
+
+		cursor, err = redis.Int(res[0], nil)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := redis.Strings(res[1], nil)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range keys {
+			// The glob is broader than the pattern, e.g. * matches dots.
+			if transport.MatchTopic(pattern, k) {
+				topics = append(topics, k)
+			}
+		}
+
+		if cursor == 0 {
+			return lists(con, topics)
+		}
+	}
+}
+
+// lists returns the keys that hold lists. Other keys matching a pattern, e.g. leases
+// or satellite state, are not topics and fail a blocking receive.
+func lists(con redis.Conn, keys []string) ([]string, error) {
+	const command = "TYPE"
+
+	for _, k := range keys {
+		err := con.Send(command, k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := con.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	var topics []string
+	for _, k := range keys {
+		typ, err := redis.String(con.Receive())
+		if err != nil {
+			return nil, err
+		}
+		if typ == "list" {
+			topics = append(topics, k)
+		}
+	}
+	return topics, nil
+}
+
+// glob converts a topic pattern to a redis glob-style pattern.
+func glob(pattern string) string {
+	tokens := strings.Split(pattern, transport.TokenSeparator)
+	for i, t := range tokens {
+		switch t {
+		case transport.SingleTokenWildcard, transport.TailWildcard:
+			tokens[i] = "*"
+		default:
+			tokens[i] = globEscaper.Replace(t)
+		}
+	}
+	return strings.Join(tokens, transport.TokenSeparator)
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// decode decodes a BRPOP reply.
+func decode(res interface{}) (topic, replyTopic string, data interface{}, err error) {
+	result, err := redis.ByteSlices(res, nil)
+	if err == redis.ErrNil {
+		return "", "", nil, transport.ErrTimeout
+	}
+	if err != nil {
+		return "", "", nil, err
 	}
 
 	if len(result) != 2 {
-		return "", nil, transport.ErrInvalidResponse
+		return "", "", nil, transport.ErrInvalidResponse
 	}
 
 	var msg message
 	err = json.Unmarshal(result[1], &msg)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 
 	data, err = base64.StdEncoding.DecodeString(msg.Data.(string))
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 
-	return msg.ReplyTopic, data, err
+	return string(result[0]), msg.ReplyTopic, data, nil
 }
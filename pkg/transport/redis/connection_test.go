@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBlockTimeout(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    int
+	}{
+		{0, 1},
+		{time.Millisecond, 1},
+		{500 * time.Millisecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{5 * time.Second, 5},
+	}
+	for _, tt := range tests {
+		if got := blockTimeout(tt.timeout); got != tt.want {
+			t.Errorf("blockTimeout(%v) = %d, want %d", tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"mission.42", "mission.42"},
+		{"mission.*.step.>", "mission.*.step.*"},
+		{"acme:mission.>", "acme:mission.*"},
+		{`m?[ss]\ion.>`, `m\?\[ss\]\\ion.*`},
+	}
+	for _, tt := range tests {
+		if got := glob(tt.pattern); got != tt.want {
+			t.Errorf("glob(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
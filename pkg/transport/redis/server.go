@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 
 	"github.com/antonkuzmenko/gogarin/pkg/transport"
 	"github.com/go-kit/kit/endpoint"
@@ -115,20 +116,39 @@ func (s Server) ServeRPC(ctx context.Context, req interface{}) interface{} {
 // ErrorEncoder is responsible for encoding an error.
 type ErrorEncoder func(context.Context, error) interface{}
 
-// DefaultErrorEncoder encodes the error to the JSON.
-func DefaultErrorEncoder(_ context.Context, err error) interface{} {
-	type response struct {
-		Ok     bool
-		Errors []error
-	}
+// ErrorResponse is the response encoded by DefaultErrorEncoder.
+type ErrorResponse struct {
+	Ok     bool
+	Errors []string
+}
 
+// DefaultErrorEncoder encodes the error to the JSON as ErrorResponse.
+func DefaultErrorEncoder(_ context.Context, err error) interface{} {
 	var buf bytes.Buffer
-	err = json.NewEncoder(&buf).Encode(response{Ok: false, Errors: []error{err}})
+	err = json.NewEncoder(&buf).Encode(ErrorResponse{Ok: false, Errors: []string{err.Error()}})
 	if err != nil {
 		buf = bytes.Buffer{}
-		res := response{Ok: false, Errors: []error{errors.New("could not encode an error")}}
+		res := ErrorResponse{Ok: false, Errors: []string{"could not encode an error"}}
 		_ = json.NewEncoder(&buf).Encode(res)
 	}
 
-	return buf
+	return buf.Bytes()
+}
+
+// DecodeError returns the error of the response encoded by DefaultErrorEncoder.
+// It returns nil if the response is not an ErrorResponse, e.g. it is a successful response.
+// Response decoders call it first, so that an error response is not decoded as a valid one.
+func DecodeError(data []byte) error {
+	var r struct {
+		Ok     *bool
+		Errors []string
+	}
+	err := json.Unmarshal(data, &r)
+	if err != nil || r.Ok == nil || *r.Ok {
+		return nil
+	}
+	if len(r.Errors) == 0 {
+		return errors.New("unknown error")
+	}
+	return errors.New(strings.Join(r.Errors, "; "))
 }
@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestDefaultErrorEncoder(t *testing.T) {
+	res := DefaultErrorEncoder(context.Background(), errors.New("no such satellite"))
+	data, ok := res.([]byte)
+	if !ok {
+		t.Fatalf("got %T, want []byte", res)
+	}
+
+	var r ErrorResponse
+	err := json.Unmarshal(data, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Ok || len(r.Errors) != 1 || r.Errors[0] != "no such satellite" {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"Ok":false,"Errors":["no such satellite"]}`, "no such satellite"},
+		{`{"Ok":false,"Errors":["a","b"]}`, "a; b"},
+		{`{"Ok":false}`, "unknown error"},
+		{`{"Ok":true}`, ""},
+		{`{"Name":"cron","Version":"1"}`, ""},
+		{`{"Errors":[{"Path":"limit","Message":"is required"}]}`, ""},
+		{`[]`, ""},
+		{`not json`, ""},
+	}
+	for _, tt := range tests {
+		err := DecodeError([]byte(tt.data))
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("DecodeError(%s) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestServeRPCError(t *testing.T) {
+	fail := errors.New("broken")
+	s := NewServer(
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, fail },
+		func(ctx context.Context, req interface{}) (interface{}, error) { return req, nil },
+		func(ctx context.Context, res interface{}) (interface{}, error) { return res, nil },
+	)
+
+	res := s.ServeRPC(context.Background(), []byte("{}"))
+	data, ok := res.([]byte)
+	if !ok {
+		t.Fatalf("got %T, want []byte", res)
+	}
+	if err := DecodeError(data); err == nil || err.Error() != "broken" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/antonkuzmenko/gogarin/pkg/transport"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// StreamServer wraps a streaming endpoint and implements a transport.StreamHandler.
+type StreamServer struct {
+	e            transport.StreamEndpoint
+	dec          transport.DecodeRequestFunc
+	enc          transport.EncodeResponseFunc
+	before       []ServerRequestFunc
+	errorEncoder ErrorEncoder
+	logger       log.Logger
+}
+
+// NewStreamServer constructs a new stream server, which implements transport.StreamHandler
+// and wraps the provided streaming endpoint. ServerAfter options are ignored.
+func NewStreamServer(
+	e transport.StreamEndpoint,
+	dec transport.DecodeRequestFunc,
+	enc transport.EncodeResponseFunc,
+	options ...ServerOption,
+) *StreamServer {
+	s := &Server{
+		dec:          dec,
+		enc:          enc,
+		errorEncoder: DefaultErrorEncoder,
+		logger:       log.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	return &StreamServer{
+		e:            e,
+		dec:          s.dec,
+		enc:          s.enc,
+		before:       s.before,
+		errorEncoder: s.errorEncoder,
+		logger:       s.logger,
+	}
+}
+
+// ServeStreamRPC implements transport.StreamHandler.
+func (s StreamServer) ServeStreamRPC(
+	ctx context.Context,
+	req interface{},
+	send func(res interface{}) error,
+) interface{} {
+	for _, f := range s.before {
+		ctx = f(ctx, req)
+	}
+
+	request, err := s.dec(ctx, req)
+	if err != nil {
+		level.Error(s.logger).Log("err", err, "context", "dec")
+		return s.errorEncoder(ctx, err)
+	}
+
+	err = s.e(ctx, request, func(response interface{}) error {
+		res, err := s.enc(ctx, response)
+		if err != nil {
+			level.Error(s.logger).Log("err", err, "context", "enc")
+			return err
+		}
+		return send(res)
+	})
+	if err != nil {
+		level.Error(s.logger).Log("err", err, "context", "endpoint")
+		return s.errorEncoder(ctx, err)
+	}
+
+	return nil
+}
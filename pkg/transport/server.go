@@ -3,7 +3,9 @@ package transport
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-kit/kit/log"
@@ -20,14 +22,109 @@ type Handler interface {
 }
 
 // NewServer constructs new RPC server.
-func NewServer(conn Connection, receiveTimeout time.Duration, l log.Logger) *Server {
-	return &Server{
+func NewServer(conn Connection, receiveTimeout time.Duration, l log.Logger, options ...ServerOption) *Server {
+	done := make(chan struct{})
+	close(done)
+
+	s := &Server{
 		conn:           conn,
 		receiveTimeout: receiveTimeout,
 		logger:         l,
-		done:           make(chan struct{}),
-		m:              make(map[string]entry),
+		minErrBackoff:  100 * time.Millisecond,
+		maxErrBackoff:  10 * time.Second,
+		streamTTL:      time.Minute,
+		state:          StateIdle,
+		done:           done,
+		m:              make(map[string]*entry),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// ServerOption sets an optional parameter for servers.
+type ServerOption func(*Server)
+
+// ServerErrorBackoff sets the delays used when Receive fails repeatedly,
+// e.g. when the message broker is down. The delay starts from min and doubles
+// on each consecutive error until it reaches max.
+// By default, the delay grows from 100ms up to 10s.
+func ServerErrorBackoff(min, max time.Duration) ServerOption {
+	return func(s *Server) {
+		s.minErrBackoff = min
+		s.maxErrBackoff = max
+	}
+}
+
+// ServerMaxInFlight limits the number of requests handled concurrently per topic.
+// A received request waits for a free slot; if the server starts draining
+// in the meantime, the request is requeued instead of being handled.
+// By default, there is no limit.
+func ServerMaxInFlight(n int) ServerOption {
+	return func(s *Server) { s.maxInFlight = n }
+}
+
+// ServerNamespace makes the server receive requests from the topics prefixed
+// with the namespace. Topics are registered with Handle and reported by Stats
+// without the namespace. Reply topics are namespaced by the client.
+func ServerNamespace(namespace string) ServerOption {
+	return func(s *Server) { s.namespace = namespace }
+}
+
+// ServerStreamTTL sets how long the reply topic of a stream is kept after its latest frame,
+// if the connection implements Expirer. The client reading the stream must receive
+// the frames within the ttl, e.g. it has closed the stream otherwise.
+// By default, the reply topic is kept for 1m.
+func ServerStreamTTL(ttl time.Duration) ServerOption {
+	return func(s *Server) { s.streamTTL = ttl }
+}
+
+// ServerState describes the lifecycle stage of a Server.
+type ServerState int32
+
+const (
+	// StateIdle is the state of a Server that has never been served.
+	StateIdle ServerState = iota
+	// StateServing is the state of a Server that receives requests.
+	StateServing
+	// StateDraining is the state of a Server that stopped receiving requests
+	// and waits for the in-flight requests to complete.
+	StateDraining
+	// StateStopped is the state of a Server that has been shut down.
+	// It can be served again.
+	StateStopped
+)
+
+func (st ServerState) String() string {
+	switch st {
+	case StateIdle:
+		return "idle"
+	case StateServing:
+		return "serving"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
 	}
+	return "unknown"
+}
+
+// Stats is a snapshot of the Server state.
+type Stats struct {
+	State  ServerState
+	Topics map[string]TopicStats
+}
+
+// TopicStats contains request counters of a single topic.
+type TopicStats struct {
+	// InFlight is a number of requests being handled at the moment.
+	InFlight int64
+	// Handled is a number of requests handled since the server was created.
+	Handled uint64
+	// Requeued is a number of requests sent back to the topic because
+	// the server started draining before they could be handled.
+	Requeued uint64
 }
 
 // Server is a RPC server.
@@ -43,103 +140,252 @@ type Server struct {
 	conn           Connection
 	receiveTimeout time.Duration
 	logger         log.Logger
+	minErrBackoff  time.Duration
+	maxErrBackoff  time.Duration
+	maxInFlight    int
+	namespace      string
+	streamTTL      time.Duration
 
-	doneMu sync.Mutex
-	done   chan struct{}
+	runMu sync.Mutex
+	state ServerState
+	run   *run
+	done  chan struct{}
 
 	mu sync.RWMutex
-	m  map[string]entry
+	m  map[string]*entry
 }
 
 type entry struct {
+	// Accessed atomically, keep them first for 64-bit alignment.
+	inFlight int64
+	handled  uint64
+	requeued uint64
+
 	h     Handler
+	sh    StreamHandler
 	topic string
-	done  chan struct{}
+	// queue is the namespaced topic the requests are received from.
+	queue   string
+	pattern bool
+}
+
+// run holds the state of a single Serve call.
+type run struct {
+	// ctx is canceled when the server starts draining.
+	ctx  context.Context
+	stop context.CancelFunc
+
+	// handlerCtx is passed to handlers, it is canceled only when
+	// the Shutdown's context expires before the drain is complete.
+	handlerCtx context.Context
+	abort      context.CancelFunc
+
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{}
 }
 
-// ErrServerClosed is returned by the Server's Serve method after a call to Shutdown.
-var ErrServerClosed = errors.New("server: Server closed")
+// close stops receiving new requests and unblocks Serve.
+func (r *run) close() {
+	r.closeOnce.Do(func() {
+		r.stop()
+		close(r.closed)
+	})
+}
+
+var (
+	// ErrServerClosed is returned by the Server's Serve method after a call to Shutdown.
+	ErrServerClosed = errors.New("server: Server closed")
+
+	// ErrServerRunning is returned by the Server's Serve method when the server
+	// is already serving or draining.
+	ErrServerRunning = errors.New("server: Server is running")
+)
+
+type contextKey int
+
+const (
+	// ContextKeyTopic is populated in the context passed to a Handler.
+	// Its value is the topic the request was received from, without the namespace.
+	// It is useful for handlers registered for a pattern.
+	ContextKeyTopic contextKey = iota
+)
 
 // Handle registers the handler for the given topic.
+// The topic may be a pattern, e.g. "mission.*.step.>", see MatchTopic.
+// A request that matches both a topic and a pattern is handled by the topic's handler.
 // If a handler already exists for topic, Handle panics.
+// If the topic is a pattern and the connection does not implement PatternReceiver, Handle panics.
 func (s *Server) Handle(topic string, handler Handler) {
+	if handler == nil {
+		panic("server: nil handler")
+	}
+	s.register(topic, &entry{h: handler})
+}
+
+// HandleStream registers the streaming handler for the given topic.
+// The handler's responses are sent to the reply topic as numbered frames
+// followed by an end-of-stream frame, see Client.Stream.
+// HandleStream panics on the same conditions as Handle.
+func (s *Server) HandleStream(topic string, handler StreamHandler) {
+	if handler == nil {
+		panic("server: nil handler")
+	}
+	s.register(topic, &entry{sh: handler})
+}
+
+func (s *Server) register(topic string, e *entry) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if topic == "" {
+	if topic == "" || !ValidPattern(topic) {
 		panic("server: invalid topic " + topic)
 	}
-	if handler == nil {
-		panic("server: nil handler")
-	}
 	_, ok := s.m[topic]
 	if ok {
 		panic("server: multiple registrations for " + topic)
 	}
 
-	s.m[topic] = entry{h: handler, topic: topic, done: make(chan struct{})}
+	e.pattern = IsPattern(topic)
+	if _, ok := s.conn.(PatternReceiver); e.pattern && !ok {
+		panic("server: connection does not support pattern topics " + topic)
+	}
+
+	e.topic = topic
+	e.queue = NamespacedTopic(s.namespace, topic)
+	s.m[topic] = e
 }
 
 // Serve responds to incoming requests, creating a new service goroutine for each topic.
 // Each service goroutine calls transport.Handler to respond to an incoming request.
+//
+// Serve blocks until Shutdown is called and then returns ErrServerClosed. It returns no other error
+// while serving: failed receives are logged and retried with a backoff, see ServerErrorBackoff.
+// It returns ErrServerRunning if the server is serving or draining already.
+// A server that has been shut down can be served again once Done is closed.
 func (s *Server) Serve() error {
-	select {
-	case <-s.done:
-		return ErrServerClosed
-	default:
+	s.runMu.Lock()
+	if s.state == StateServing || s.state == StateDraining {
+		s.runMu.Unlock()
+		return ErrServerRunning
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	s.serve(ctx)
+	r := &run{closed: make(chan struct{}), done: make(chan struct{})}
+	r.ctx, r.stop = context.WithCancel(context.Background())
+	r.handlerCtx, r.abort = context.WithCancel(context.Background())
+	s.run = r
+	s.done = r.done
+	s.state = StateServing
+	s.runMu.Unlock()
+
+	s.serve(r)
 
-	<-s.done
-	cancel()
-	return nil
+	<-r.closed
+	return ErrServerClosed
 }
 
-func (s *Server) serve(ctx context.Context) {
+func (s *Server) serve(r *run) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	var topics sync.WaitGroup
 	for _, item := range s.m {
-		level.Info(s.logger).Log("serve", item.topic)
-		go s.handle(ctx, item.topic, item.h, item.done)
+		level.Info(s.logger).Log("serve", item.queue)
+		topics.Add(1)
+		go func(e *entry) {
+			defer topics.Done()
+			s.handle(r, e)
+		}(item)
 	}
+
+	go func() {
+		<-r.closed
+		topics.Wait()
+		r.abort()
+
+		s.runMu.Lock()
+		s.state = StateStopped
+		close(r.done)
+		s.runMu.Unlock()
+	}()
 }
 
-func (s *Server) handle(ctx context.Context, topic string, h Handler, done chan<- struct{}) {
+func (s *Server) handle(r *run, e *entry) {
 	var requests sync.WaitGroup
+	b := NewBackoff(s.minErrBackoff, s.maxErrBackoff)
+
+	var slots chan struct{}
+	if s.maxInFlight > 0 {
+		slots = make(chan struct{}, s.maxInFlight)
+	}
 
 	for {
 		select {
-		case <-ctx.Done():
+		case <-r.ctx.Done():
+			level.Info(s.logger).Log("draining", e.queue, "in_flight", atomic.LoadInt64(&e.inFlight))
 			requests.Wait()
-			level.Info(s.logger).Log("done", topic)
-			done <- struct{}{}
+			level.Info(s.logger).Log("done", e.queue)
 			return
 		default:
 		}
 
-		replyTo, data, err := s.conn.Receive(topic, s.receiveTimeout)
+		queue, replyTo, data, err := s.receive(e)
 		if err == ErrTimeout {
+			b.Reset()
 			continue
 		}
 		if err != nil {
-			level.Error(s.logger).Log("err", err)
+			level.Error(s.logger).Log("err", err, "topic", e.queue, "attempt", b.Attempt()+1)
+			_ = b.Wait(r.ctx)
+			continue
+		}
+		b.Reset()
+
+		if slots != nil {
+			select {
+			case slots <- struct{}{}:
+			case <-r.ctx.Done():
+				s.requeue(e, queue, replyTo, data)
+				continue
+			}
+		}
+
+		select {
+		case <-r.ctx.Done():
+			// The request has been received after the drain started.
+			if slots != nil {
+				<-slots
+			}
+			s.requeue(e, queue, replyTo, data)
 			continue
+		default:
 		}
 
+		atomic.AddInt64(&e.inFlight, 1)
 		requests.Add(1)
 		go func() {
 			defer requests.Done()
+			defer func() {
+				atomic.AddInt64(&e.inFlight, -1)
+				atomic.AddUint64(&e.handled, 1)
+				if slots != nil {
+					<-slots
+				}
+			}()
 			defer func() {
 				if err := recover(); err != nil {
-					level.Error(s.logger).Log("err", err, "serving", topic)
+					level.Error(s.logger).Log("err", err, "serving", e.queue)
 				}
 			}()
 
-			res := h.ServeRPC(ctx, data)
-			err = s.conn.Send(replyTo, NoReply, res)
+			ctx := context.WithValue(r.handlerCtx, ContextKeyTopic, s.topic(queue))
+			if e.sh != nil {
+				s.serveStream(ctx, e, replyTo, data)
+				return
+			}
+
+			res := e.h.ServeRPC(ctx, data)
+			err := s.conn.Send(replyTo, NoReply, res)
 			if err != nil {
 				level.Error(s.logger).Log("err", err)
 			}
@@ -147,33 +393,116 @@ func (s *Server) handle(ctx context.Context, topic string, h Handler, done chan<
 	}
 }
 
+func (s *Server) serveStream(ctx context.Context, e *entry, replyTo string, data interface{}) {
+	w := &streamWriter{conn: s.conn, replyTopic: replyTo, ttl: s.streamTTL}
+	res := e.sh.ServeStreamRPC(ctx, data, w.send)
+	err := w.end(res)
+	if err != nil {
+		level.Error(s.logger).Log("err", err, "context", "stream", "topic", e.queue)
+	}
+}
+
+// receive receives a request for the entry and reports the namespaced topic
+// it was received from.
+func (s *Server) receive(e *entry) (queue, replyTo string, data interface{}, err error) {
+	if !e.pattern {
+		replyTo, data, err = s.conn.Receive(e.queue, s.receiveTimeout)
+		return e.queue, replyTo, data, err
+	}
+
+	return s.conn.(PatternReceiver).ReceivePattern(e.queue, s.queues(), s.receiveTimeout)
+}
+
+// queues returns the namespaced topics registered without wildcards.
+func (s *Server) queues() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var queues []string
+	for _, e := range s.m {
+		if !e.pattern {
+			queues = append(queues, e.queue)
+		}
+	}
+	return queues
+}
+
+// topic strips the namespace from the topic.
+func (s *Server) topic(queue string) string {
+	if s.namespace == "" {
+		return queue
+	}
+	return strings.TrimPrefix(queue, s.namespace+NamespaceSeparator)
+}
+
+// requeue sends the request back to its topic, so that another instance
+// or the next Serve call can handle it. If the connection implements Requeuer,
+// the request is received before the requests that came after it.
+func (s *Server) requeue(e *entry, queue, replyTo string, data interface{}) {
+	var err error
+	if r, ok := s.conn.(Requeuer); ok {
+		err = r.Requeue(queue, replyTo, data)
+	} else {
+		err = s.conn.Send(queue, replyTo, data)
+	}
+	if err != nil {
+		level.Error(s.logger).Log("err", err, "context", "requeue", "topic", queue)
+		return
+	}
+	atomic.AddUint64(&e.requeued, 1)
+}
+
 // Shutdown gracefully shuts down the server without interrupting any active
 // service goroutines. If the provided context expires before the shutdown is complete,
-// Shutdown returns the context's error.
+// Shutdown cancels the context passed to the active handlers and returns the context's error.
 //
 // When Shutdown is called, Serve immediately returns ErrServerClosed. Make sure the
 // program doesn't exit and waits instead for Shutdown to return.
 func (s *Server) Shutdown(ctx context.Context) error {
-	s.doneMu.Lock()
-	defer s.doneMu.Unlock()
+	s.runMu.Lock()
+	r := s.run
+	if r == nil {
+		// Never served.
+		s.runMu.Unlock()
+		return nil
+	}
+	if s.state == StateServing {
+		s.state = StateDraining
+	}
+	r.close()
+	s.runMu.Unlock()
 
 	select {
-	case <-s.done:
-		// Already closed. Don't close again.
+	case <-ctx.Done():
+		r.abort()
+		return ctx.Err()
+	case <-r.done:
 		return nil
-	default:
-		close(s.done)
 	}
+}
+
+// Done returns a channel that is closed when the server is not serving
+// and all the in-flight requests are complete.
+func (s *Server) Done() <-chan struct{} {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	return s.done
+}
+
+// Stats returns the current state of the server and its topics.
+func (s *Server) Stats() Stats {
+	s.runMu.Lock()
+	st := Stats{State: s.state, Topics: make(map[string]TopicStats)}
+	s.runMu.Unlock()
 
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, item := range s.m {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-item.done:
+	for topic, e := range s.m {
+		st.Topics[topic] = TopicStats{
+			InFlight: atomic.LoadInt64(&e.inFlight),
+			Handled:  atomic.LoadUint64(&e.handled),
+			Requeued: atomic.LoadUint64(&e.requeued),
 		}
 	}
-
-	return nil
+	return st
 }
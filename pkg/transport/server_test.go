@@ -0,0 +1,287 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type handlerFunc func(ctx context.Context, req interface{}) interface{}
+
+func (f handlerFunc) ServeRPC(ctx context.Context, req interface{}) interface{} {
+	return f(ctx, req)
+}
+
+// requeueConn is a memConn that implements Requeuer.
+type requeueConn struct {
+	*memConn
+}
+
+func (r requeueConn) Requeue(topic, replyTopic string, data interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.topics[topic] = append([]memMessage{{replyTopic, data}}, r.topics[topic]...)
+	return nil
+}
+
+// waitFor fails the test if the condition is not met within a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func serve(s *Server) <-chan error {
+	served := make(chan error, 1)
+	go func() { served <- s.Serve() }()
+	return served
+}
+
+func echoHandler() Handler {
+	return handlerFunc(func(ctx context.Context, req interface{}) interface{} { return req })
+}
+
+func TestServerStates(t *testing.T) {
+	conn := newMemConn()
+	s := NewServer(conn, 5*time.Millisecond, log.NewNopLogger())
+	s.Handle("echo", echoHandler())
+	c := NewClient(conn, "echo", time.Second, passthrough, decodeString)
+
+	if st := s.Stats().State; st != StateIdle {
+		t.Errorf("got %s, want idle", st)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("got %v shutting down an idle server", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		served := serve(s)
+		waitFor(t, "serving", func() bool { return s.Stats().State == StateServing })
+		if err := s.Serve(); err != ErrServerRunning {
+			t.Errorf("got %v serving twice, want ErrServerRunning", err)
+		}
+
+		res, err := c.Endpoint()(context.Background(), []byte("ping"))
+		if err != nil || res != "ping" {
+			t.Errorf("got %v, %v", res, err)
+		}
+
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-served; err != ErrServerClosed {
+			t.Errorf("got %v, want ErrServerClosed", err)
+		}
+		if st := s.Stats().State; st != StateStopped {
+			t.Errorf("got %s, want stopped", st)
+		}
+		select {
+		case <-s.Done():
+		default:
+			t.Error("Done is not closed after Shutdown")
+		}
+	}
+
+	if n := s.Stats().Topics["echo"].Handled; n != 2 {
+		t.Errorf("got %d handled requests, want 2", n)
+	}
+}
+
+func TestServerDrain(t *testing.T) {
+	conn := newMemConn()
+	s := NewServer(conn, 5*time.Millisecond, log.NewNopLogger())
+
+	release := make(chan struct{})
+	var handlerErr error
+	s.Handle("work", handlerFunc(func(ctx context.Context, req interface{}) interface{} {
+		<-release
+		handlerErr = ctx.Err()
+		return req
+	}))
+	served := serve(s)
+
+	if err := conn.Send("work", "reply", []byte("job")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the request", func() bool { return s.Stats().Topics["work"].InFlight == 1 })
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+
+	// Serve returns at once, Shutdown waits for the request.
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("got %v, want ErrServerClosed", err)
+	}
+	waitFor(t, "draining", func() bool { return s.Stats().State == StateDraining })
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the request is complete", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Errorf("got %v", err)
+	}
+	if handlerErr != nil {
+		t.Errorf("the handler's context is done while draining: %v", handlerErr)
+	}
+	if n := conn.len("reply"); n != 1 {
+		t.Errorf("got %d replies, want 1", n)
+	}
+
+	st := s.Stats()
+	if st.State != StateStopped || st.Topics["work"] != (TopicStats{Handled: 1}) {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	conn := newMemConn()
+	s := NewServer(conn, 5*time.Millisecond, log.NewNopLogger())
+
+	canceled := make(chan error, 1)
+	s.Handle("work", handlerFunc(func(ctx context.Context, req interface{}) interface{} {
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return req
+	}))
+	served := serve(s)
+
+	if err := conn.Send("work", "reply", []byte("job")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the request", func() bool { return s.Stats().Topics["work"].InFlight == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+	if err := <-canceled; err != context.Canceled {
+		t.Errorf("got %v in the handler, want context.Canceled", err)
+	}
+	<-served
+	<-s.Done()
+}
+
+func TestServerRequeue(t *testing.T) {
+	conn := requeueConn{newMemConn()}
+	s := NewServer(conn, 5*time.Millisecond, log.NewNopLogger(), ServerMaxInFlight(1))
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []string
+	s.Handle("work", handlerFunc(func(ctx context.Context, req interface{}) interface{} {
+		mu.Lock()
+		handled = append(handled, string(req.([]byte)))
+		mu.Unlock()
+		<-release
+		return req
+	}))
+	served := serve(s)
+
+	for _, job := range []string{"first", "second", "third"} {
+		if err := conn.Send("work", "reply", []byte(job)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first request is handled, the second one waits for a free slot.
+	waitFor(t, "the requests", func() bool {
+		return s.Stats().Topics["work"].InFlight == 1 && conn.len("work") == 1
+	})
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- s.Shutdown(context.Background()) }()
+	waitFor(t, "the requeue", func() bool { return s.Stats().Topics["work"].Requeued == 1 })
+
+	close(release)
+	if err := <-shutdown; err != nil {
+		t.Fatal(err)
+	}
+	<-served
+
+	if len(handled) != 1 || handled[0] != "first" {
+		t.Errorf("got %v handled", handled)
+	}
+	for _, want := range []string{"second", "third"} {
+		_, data, err := conn.Receive("work", 0)
+		if err != nil || string(data.([]byte)) != want {
+			t.Errorf("got %v, %v, want %s", data, err, want)
+		}
+	}
+
+	st := s.Stats().Topics["work"]
+	if st != (TopicStats{Handled: 1, Requeued: 1}) {
+		t.Errorf("got %+v", st)
+	}
+}
+
+func TestServerNamespace(t *testing.T) {
+	conn := newMemConn()
+	var servers []*Server
+	for _, ns := range []string{"", "acme", "other"} {
+		ns := ns
+		s := NewServer(conn, 5*time.Millisecond, log.NewNopLogger(), ServerNamespace(ns))
+		s.Handle("whoami", handlerFunc(func(ctx context.Context, req interface{}) interface{} {
+			return []byte(ns + "/" + ctx.Value(ContextKeyTopic).(string))
+		}))
+		served := serve(s)
+		defer func() {
+			_ = s.Shutdown(context.Background())
+			<-served
+		}()
+		servers = append(servers, s)
+	}
+
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{"", "/whoami"},
+		{"acme", "acme/whoami"},
+		{"other", "other/whoami"},
+	}
+	for _, tt := range tests {
+		c := NewClient(conn, "whoami", time.Second, passthrough, decodeString, ClientNamespace(tt.namespace))
+		if c.topic != NamespacedTopic(tt.namespace, "whoami") {
+			t.Errorf("got the client topic %q", c.topic)
+		}
+		res, err := c.Endpoint()(context.Background(), []byte("?"))
+		if err != nil || res != tt.want {
+			t.Errorf("namespace %q: got %v, %v, want %s", tt.namespace, res, err, tt.want)
+		}
+	}
+
+	for _, s := range servers {
+		st, ok := s.Stats().Topics["whoami"]
+		if !ok || st.Handled != 1 {
+			t.Errorf("got %+v, want the topic without the namespace handled once", s.Stats().Topics)
+		}
+	}
+}
+
+func TestServerTopic(t *testing.T) {
+	s := NewServer(newMemConn(), time.Millisecond, log.NewNopLogger(), ServerNamespace("acme"))
+	tests := []struct {
+		queue string
+		want  string
+	}{
+		{"acme:mission.42.step", "mission.42.step"},
+		{"acme:acme:mission", "acme:mission"},
+		{"other:mission", "other:mission"},
+		{"mission", "mission"},
+	}
+	for _, tt := range tests {
+		if got := s.topic(tt.queue); got != tt.want {
+			t.Errorf("topic(%q) = %q, want %q", tt.queue, got, tt.want)
+		}
+	}
+}
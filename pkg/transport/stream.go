@@ -0,0 +1,250 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrInvalidFrame is returned when a stream frame is corrupted or arrives out of order.
+var ErrInvalidFrame = errors.New("invalid stream frame")
+
+// StreamHandler responds to an RPC request with multiple responses.
+//
+// ServeStreamRPC calls send for every response. The returned res, if not nil,
+// is sent as the last response of the stream, e.g. an encoded error.
+// Responses must be []byte.
+type StreamHandler interface {
+	ServeStreamRPC(ctx context.Context, req interface{}, send func(res interface{}) error) (res interface{})
+}
+
+// StreamEndpoint is the streaming counterpart of endpoint.Endpoint.
+// It calls send for every response and returns when the stream is over.
+type StreamEndpoint func(ctx context.Context, request interface{}, send func(response interface{}) error) error
+
+// frame is a single response of a stream.
+type frame struct {
+	Seq  int    `json:"seq"`
+	Data []byte `json:"data,omitempty"`
+	End  bool   `json:"end,omitempty"`
+}
+
+func encodeFrame(f frame) ([]byte, error) {
+	return json.Marshal(f)
+}
+
+func decodeFrame(data interface{}) (f frame, err error) {
+	b, ok := data.([]byte)
+	if !ok {
+		return f, ErrInvalidFrame
+	}
+	err = json.Unmarshal(b, &f)
+	return f, err
+}
+
+// streamWriter sends the responses of a StreamHandler as numbered frames.
+// If the connection implements Expirer, the reply topic expires after the ttl
+// without new frames, e.g. when the client has closed the stream.
+type streamWriter struct {
+	conn       Connection
+	replyTopic string
+	ttl        time.Duration
+	seq        int
+}
+
+func (w *streamWriter) send(res interface{}) error {
+	data, ok := res.([]byte)
+	if !ok {
+		return ErrInvalidFrame
+	}
+	return w.write(frame{Data: data})
+}
+
+func (w *streamWriter) end(res interface{}) error {
+	f := frame{End: true}
+	if res != nil {
+		data, ok := res.([]byte)
+		if !ok {
+			return ErrInvalidFrame
+		}
+		f.Data = data
+	}
+	return w.write(f)
+}
+
+func (w *streamWriter) write(f frame) error {
+	f.Seq = w.seq
+	w.seq++
+
+	data, err := encodeFrame(f)
+	if err != nil {
+		return err
+	}
+	err = w.conn.Send(w.replyTopic, NoReply, data)
+	if err != nil {
+		return err
+	}
+
+	if e, ok := w.conn.(Expirer); ok && w.ttl > 0 {
+		return e.Expire(w.replyTopic, w.ttl)
+	}
+	return nil
+}
+
+// streamPollTimeout limits a single wait for a frame, so that Recv notices the canceled context.
+// It is the shortest timeout of the blocking receive of redis.
+const streamPollTimeout = time.Second
+
+// Stream is a stream of responses returned by Client.Stream.
+type Stream struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	c      *Client
+	topic  string
+	seq    int
+	err    error
+}
+
+// Recv returns the next decoded response of the stream.
+// It returns io.EOF when the stream is over. If the context passed to
+// Client.Stream is canceled, Recv returns the context's error.
+func (s *Stream) Recv() (res interface{}, err error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	data, err := s.receive()
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+
+	f, err := decodeFrame(data)
+	if err != nil {
+		s.err = err
+		return nil, err
+	}
+	if f.Seq != s.seq {
+		s.err = ErrInvalidFrame
+		return nil, s.err
+	}
+	s.seq++
+
+	if f.End {
+		// The next Recv reports the end of the stream.
+		s.err = io.EOF
+		if f.Data == nil {
+			return nil, s.err
+		}
+	}
+	return s.decode(f.Data)
+}
+
+// receive waits for the next frame up to the Client's receiveTimeout. It polls the reply topic
+// and checks the context in between, so that a received frame is never discarded.
+func (s *Stream) receive() (data interface{}, err error) {
+	timeout := streamPollTimeout
+	if s.c.receiveTimeout < timeout {
+		timeout = s.c.receiveTimeout
+	}
+
+	deadline := time.Now().Add(s.c.receiveTimeout)
+	for {
+		if err := s.ctx.Err(); err != nil {
+			return nil, err
+		}
+		err = s.c.retry(s.ctx, func() (err error) {
+			_, data, err = s.c.conn.Receive(s.topic, timeout)
+			return err
+		})
+		if err == ErrTimeout && time.Now().Before(deadline) {
+			continue
+		}
+		return data, err
+	}
+}
+
+func (s *Stream) decode(data []byte) (res interface{}, err error) {
+	ctx := s.ctx
+	for _, f := range s.c.after {
+		ctx = f(ctx, data)
+	}
+	return s.c.dec(ctx, data)
+}
+
+// Close cancels the stream. Responses that have not been received are discarded:
+// if the connection implements Expirer, the reply topic is deleted, and the responses
+// sent afterwards expire with it, see ServerStreamTTL.
+func (s *Stream) Close() {
+	s.cancel()
+	if s.err == io.EOF {
+		return
+	}
+	if s.err == nil {
+		s.err = context.Canceled
+	}
+	if e, ok := s.c.conn.(Expirer); ok {
+		_ = e.Expire(s.topic, 0)
+	}
+}
+
+// Stream invokes the remote streaming endpoint registered with Server.HandleStream
+// and returns the stream of its responses. Each response waits at most
+// the Client's receiveTimeout.
+func (c *Client) Stream(ctx context.Context, req interface{}) (*Stream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	request, err := c.enc(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	for _, f := range c.before {
+		ctx = f(ctx, req)
+	}
+
+	replyTopic, err := createReplyTopic(c.topic)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	err = c.retry(ctx, func() error {
+		return c.conn.Send(c.topic, replyTopic, request)
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &Stream{ctx: ctx, cancel: cancel, c: c, topic: replyTopic}, nil
+}
+
+// StreamEndpoint returns a usable StreamEndpoint that invokes the remote streaming endpoint.
+func (c *Client) StreamEndpoint() StreamEndpoint {
+	return func(ctx context.Context, req interface{}, send func(response interface{}) error) error {
+		s, err := c.Stream(ctx, req)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+
+		for {
+			res, err := s.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			err = send(res)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
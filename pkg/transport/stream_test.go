@@ -0,0 +1,291 @@
+package transport
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+type memMessage struct {
+	replyTopic string
+	data       interface{}
+}
+
+// memConn is an in-memory Connection that implements Expirer.
+type memConn struct {
+	mu      sync.Mutex
+	topics  map[string][]memMessage
+	expires map[string]time.Duration
+}
+
+func newMemConn() *memConn {
+	return &memConn{topics: make(map[string][]memMessage), expires: make(map[string]time.Duration)}
+}
+
+func (m *memConn) Send(topic, replyTopic string, data interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.topics[topic] = append(m.topics[topic], memMessage{replyTopic, data})
+	return nil
+}
+
+func (m *memConn) Receive(topic string, timeout time.Duration) (string, interface{}, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		m.mu.Lock()
+		if q := m.topics[topic]; len(q) > 0 {
+			m.topics[topic] = q[1:]
+			m.mu.Unlock()
+			return q[0].replyTopic, q[0].data, nil
+		}
+		m.mu.Unlock()
+
+		if !time.Now().Before(deadline) {
+			return "", nil, ErrTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (m *memConn) Ping() error {
+	return nil
+}
+
+func (m *memConn) Expire(topic string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expires[topic] = ttl
+	if ttl == 0 {
+		delete(m.topics, topic)
+	}
+	return nil
+}
+
+func (m *memConn) expire(topic string) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ttl, ok := m.expires[topic]
+	return ttl, ok
+}
+
+func (m *memConn) len(topic string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.topics[topic])
+}
+
+type sendFunc func(res interface{}) error
+
+type streamFunc func(ctx context.Context, req interface{}, send sendFunc) interface{}
+
+func (f streamFunc) ServeStreamRPC(ctx context.Context, req interface{}, send func(res interface{}) error) interface{} {
+	return f(ctx, req, send)
+}
+
+func passthrough(ctx context.Context, v interface{}) (interface{}, error) {
+	return v, nil
+}
+
+func decodeString(ctx context.Context, v interface{}) (interface{}, error) {
+	return string(v.([]byte)), nil
+}
+
+func newStreamClient(conn Connection, receiveTimeout time.Duration) *Client {
+	return NewClient(conn, "numbers", receiveTimeout, passthrough, decodeString)
+}
+
+func TestStream(t *testing.T) {
+	conn := newMemConn()
+	s := NewServer(conn, 10*time.Millisecond, log.NewNopLogger(), ServerStreamTTL(time.Minute))
+	s.HandleStream("numbers", streamFunc(func(ctx context.Context, req interface{}, send sendFunc) interface{} {
+		for _, n := range []string{"one", "two"} {
+			if err := send([]byte(n)); err != nil {
+				return nil
+			}
+		}
+		return []byte("three")
+	}))
+	go s.Serve()                           // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	st, err := newStreamClient(conn, time.Second).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	for _, want := range []string{"one", "two", "three"} {
+		res, err := st.Recv()
+		if err != nil || res != want {
+			t.Fatalf("got %v, %v, want %s", res, err, want)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if res, err := st.Recv(); err != io.EOF {
+			t.Fatalf("got %v, %v, want EOF", res, err)
+		}
+	}
+
+	if ttl, _ := conn.expire(st.topic); ttl != time.Minute {
+		t.Errorf("got the reply topic ttl %v, want 1m", ttl)
+	}
+}
+
+func TestStreamEndWithoutData(t *testing.T) {
+	conn := newMemConn()
+	st, err := newStreamClient(conn, time.Second).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &streamWriter{conn: conn, replyTopic: st.topic}
+	if err := w.end(nil); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := st.Recv(); err != io.EOF {
+		t.Errorf("got %v, %v, want EOF", res, err)
+	}
+	if _, ok := conn.expire(st.topic); ok {
+		t.Error("the reply topic expires without a ttl")
+	}
+}
+
+func TestStreamFrameOrder(t *testing.T) {
+	conn := newMemConn()
+	st, err := newStreamClient(conn, time.Second).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &streamWriter{conn: conn, replyTopic: st.topic, seq: 1}
+	if err := w.send([]byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.end(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := st.Recv(); err != ErrInvalidFrame {
+		t.Errorf("got %v, %v, want ErrInvalidFrame", res, err)
+	}
+	if res, err := st.Recv(); err != ErrInvalidFrame {
+		t.Errorf("got %v, %v after an invalid frame, want ErrInvalidFrame", res, err)
+	}
+
+	w = &streamWriter{conn: conn, replyTopic: st.topic}
+	if err := w.send("not bytes"); err != ErrInvalidFrame {
+		t.Errorf("got %v for a response that is not []byte, want ErrInvalidFrame", err)
+	}
+}
+
+func TestStreamTimeout(t *testing.T) {
+	conn := newMemConn()
+	st, err := newStreamClient(conn, 20*time.Millisecond).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := st.Recv(); err != ErrTimeout {
+		t.Errorf("got %v, %v, want ErrTimeout", res, err)
+	}
+}
+
+func TestStreamCancel(t *testing.T) {
+	conn := newMemConn()
+	ctx, cancel := context.WithCancel(context.Background())
+	st, err := newStreamClient(conn, time.Minute).Stream(ctx, []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if res, err := st.Recv(); err != context.Canceled {
+		t.Errorf("got %v, %v, want context.Canceled", res, err)
+	}
+	if d := time.Since(start); d > 2*streamPollTimeout {
+		t.Errorf("Recv noticed the canceled context after %v", d)
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	conn := newMemConn()
+	st, err := newStreamClient(conn, time.Second).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &streamWriter{conn: conn, replyTopic: st.topic, ttl: time.Minute}
+	if err := w.send([]byte("one")); err != nil {
+		t.Fatal(err)
+	}
+	st.Close()
+
+	if ttl, ok := conn.expire(st.topic); !ok || ttl != 0 {
+		t.Errorf("got the reply topic ttl %v, %t, want it deleted", ttl, ok)
+	}
+	if n := conn.len(st.topic); n != 0 {
+		t.Errorf("got %d frames left after Close", n)
+	}
+	if res, err := st.Recv(); err != context.Canceled {
+		t.Errorf("got %v, %v after Close, want context.Canceled", res, err)
+	}
+
+	// The frames sent after Close expire with the reply topic.
+	if err := w.send([]byte("two")); err != nil {
+		t.Fatal(err)
+	}
+	if ttl, _ := conn.expire(st.topic); ttl != time.Minute {
+		t.Errorf("got the reply topic ttl %v after Close, want 1m", ttl)
+	}
+}
+
+func TestStreamCloseAfterEnd(t *testing.T) {
+	conn := newMemConn()
+	st, err := newStreamClient(conn, time.Second).Stream(context.Background(), []byte("count"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &streamWriter{conn: conn, replyTopic: st.topic}
+	if err := w.end(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Recv(); err != io.EOF {
+		t.Fatalf("got %v, want EOF", err)
+	}
+	st.Close()
+
+	if _, ok := conn.expire(st.topic); ok {
+		t.Error("the reply topic of a complete stream is deleted on Close")
+	}
+	if _, err := st.Recv(); err != io.EOF {
+		t.Errorf("got %v after Close, want EOF", err)
+	}
+}
+
+func TestStreamEndpoint(t *testing.T) {
+	conn := newMemConn()
+	s := NewServer(conn, 10*time.Millisecond, log.NewNopLogger())
+	s.HandleStream("numbers", streamFunc(func(ctx context.Context, req interface{}, send sendFunc) interface{} {
+		_ = send([]byte("one"))
+		_ = send([]byte("two"))
+		return nil
+	}))
+	go s.Serve()                           // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	var got []string
+	e := newStreamClient(conn, time.Second).StreamEndpoint()
+	err := e(context.Background(), []byte("count"), func(res interface{}) error {
+		got = append(got, res.(string))
+		return nil
+	})
+	if err != nil || len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("got %v, %v", got, err)
+	}
+}
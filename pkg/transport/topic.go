@@ -0,0 +1,102 @@
+package transport
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// TokenSeparator separates the tokens of a topic, e.g. "mission.42.step.send".
+	TokenSeparator = "."
+
+	// SingleTokenWildcard matches exactly one token of a topic.
+	SingleTokenWildcard = "*"
+
+	// TailWildcard matches one or more trailing tokens of a topic.
+	// It can only be the last token of a pattern.
+	TailWildcard = ">"
+
+	replySeparator = ":reply:"
+)
+
+// PatternReceiver is implemented by connections that can receive requests
+// from every topic matching a pattern, see MatchTopic.
+// Message brokers that support wildcard subscriptions implement it natively,
+// others may emulate it by discovering the existing topics.
+type PatternReceiver interface {
+	// ReceivePattern receives data from any topic matching the pattern,
+	// except the listed topics, and reports the topic the data was received from.
+	ReceivePattern(pattern string, except []string, timeout time.Duration) (
+		topic, replyTopic string, data interface{}, err error,
+	)
+}
+
+// IsPattern reports whether the topic contains wildcards.
+func IsPattern(topic string) bool {
+	for _, t := range strings.Split(topic, TokenSeparator) {
+		if t == SingleTokenWildcard || t == TailWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPattern reports whether the pattern is well-formed: it has no empty
+// tokens and the TailWildcard, if any, is the last token.
+func ValidPattern(pattern string) bool {
+	tokens := strings.Split(pattern, TokenSeparator)
+	for i, t := range tokens {
+		if t == "" {
+			return false
+		}
+		if t == TailWildcard && i != len(tokens)-1 {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchTopic reports whether the topic matches the pattern.
+// The pattern "mission.*.step.>" matches "mission.42.step.send" and
+// "mission.42.step.send.mail", but not "mission.step.send".
+// Wildcards never match a token containing the NamespaceSeparator, so patterns
+// do not leak into other namespaces, and reply topics never match a pattern.
+func MatchTopic(pattern, topic string) bool {
+	if IsReplyTopic(topic) {
+		return false
+	}
+
+	p := strings.Split(pattern, TokenSeparator)
+	t := strings.Split(topic, TokenSeparator)
+	for i, token := range p {
+		if token == TailWildcard {
+			if len(t) <= i {
+				return false
+			}
+			for _, tail := range t[i:] {
+				if strings.Contains(tail, NamespaceSeparator) {
+					return false
+				}
+			}
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if token == SingleTokenWildcard {
+			if strings.Contains(t[i], NamespaceSeparator) {
+				return false
+			}
+			continue
+		}
+		if token != t[i] {
+			return false
+		}
+	}
+	return len(p) == len(t)
+}
+
+// IsReplyTopic reports whether the topic is a reply topic created by a Client.
+func IsReplyTopic(topic string) bool {
+	return strings.Contains(topic, replySeparator)
+}
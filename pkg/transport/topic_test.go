@@ -0,0 +1,108 @@
+package transport
+
+import "testing"
+
+func TestMatchTopic(t *testing.T) {
+	tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{"mission.42.step.send", "mission.42.step.send", true},
+		{"mission.42.step.send", "mission.42.step", false},
+		{"mission.*.step.send", "mission.42.step.send", true},
+		{"mission.*.step.send", "mission.step.send", false},
+		{"mission.*", "mission.42.step", false},
+		{"*", "mission", true},
+		{"*.*", "mission", false},
+		{"mission.*.step.>", "mission.42.step.send", true},
+		{"mission.*.step.>", "mission.42.step.send.mail", true},
+		{"mission.*.step.>", "mission.42.step", false},
+		{"mission.*.step.>", "mission.step.send", false},
+		{">", "mission.42", true},
+		{"mission.>", "missions.42", false},
+
+		// Wildcards do not cross namespaces.
+		{"*.register", "acme:satellite.register", false},
+		{">", "acme:satellite.register", false},
+		{"satellite.>", "satellite.acme:register", false},
+		{"acme:satellite.*", "acme:satellite.register", true},
+		{"acme:satellite.>", "other:satellite.register", false},
+
+		// Reply topics never match.
+		{"mission.>", "mission.42:reply:01BX5ZZKBKACTAV9WEVGEMMVRZ", false},
+		{"mission.*", "mission.42:reply:01BX5ZZKBKACTAV9WEVGEMMVRZ", false},
+	}
+	for _, tt := range tests {
+		if got := MatchTopic(tt.pattern, tt.topic); got != tt.want {
+			t.Errorf("MatchTopic(%q, %q) = %t, want %t", tt.pattern, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestValidPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{"mission", true},
+		{"mission.42.step", true},
+		{"mission.*.step", true},
+		{"mission.*.>", true},
+		{">", true},
+		{"*", true},
+		{"acme:mission.>", true},
+		{"", false},
+		{"mission.", false},
+		{".mission", false},
+		{"mission..step", false},
+		{"mission.>.step", false},
+		{">.>", false},
+	}
+	for _, tt := range tests {
+		if got := ValidPattern(tt.pattern); got != tt.want {
+			t.Errorf("ValidPattern(%q) = %t, want %t", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestIsPattern(t *testing.T) {
+	tests := []struct {
+		topic string
+		want  bool
+	}{
+		{"mission.42", false},
+		{"mission.*", true},
+		{"mission.>", true},
+		{"mission*.42", false},
+		{"mission.a>b", false},
+	}
+	for _, tt := range tests {
+		if got := IsPattern(tt.topic); got != tt.want {
+			t.Errorf("IsPattern(%q) = %t, want %t", tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestIsReplyTopic(t *testing.T) {
+	reply, err := createReplyTopic(NamespacedTopic("acme", "satellite.register"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		topic string
+		want  bool
+	}{
+		{reply, true},
+		{"satellite.register:reply:01BX5ZZKBKACTAV9WEVGEMMVRZ", true},
+		{"satellite.register", false},
+		{"acme:satellite.register", false},
+		{"reply.satellite", false},
+	}
+	for _, tt := range tests {
+		if got := IsReplyTopic(tt.topic); got != tt.want {
+			t.Errorf("IsReplyTopic(%q) = %t, want %t", tt.topic, got, tt.want)
+		}
+	}
+}